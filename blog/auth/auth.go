@@ -0,0 +1,109 @@
+// Package auth identifies the callers of the blog service from the bearer
+// token they send in the request metadata.
+package auth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// AdminRole is the role that lets a caller change blogs written by others.
+const AdminRole = "admin"
+
+// ErrInvalidToken is returned for a bearer token that is not known.
+var ErrInvalidToken = errors.New("auth: invalid token")
+
+// Identity is the caller a bearer token was issued to.
+type Identity struct {
+	Subject string
+	Roles   []string
+}
+
+// IsAdmin reports whether the identity has the admin role.
+func (id *Identity) IsAdmin() bool {
+	for _, role := range id.Roles {
+		if role == AdminRole {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator validates bearer tokens.
+type Authenticator interface {
+	// Authenticate returns the identity the token was issued to, or
+	// ErrInvalidToken.
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// TokenTable is an Authenticator backed by a fixed set of tokens.
+type TokenTable map[string]*Identity
+
+func (t TokenTable) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	id, ok := t[token]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return id, nil
+}
+
+// ReadTokens parses a token table with one token per line, followed by the
+// subject it identifies and any roles, separated by spaces:
+//
+//	# token   subject  roles...
+//	s3cr3t    khoi
+//	t0ps3cr3t root     admin
+//
+// Blank lines and lines starting with # are ignored.
+func ReadTokens(r io.Reader) (TokenTable, error) {
+	table := TokenTable{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("auth: line %d: want a token and a subject", n)
+		}
+		if _, ok := table[fields[0]]; ok {
+			return nil, fmt.Errorf("auth: line %d: duplicate token", n)
+		}
+		table[fields[0]] = &Identity{Subject: fields[1], Roles: fields[2:]}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// LoadTokens reads a token table from the file at path, see ReadTokens.
+func LoadTokens(path string) (TokenTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadTokens(f)
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying the caller's identity.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the caller's identity stored in ctx, if any.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadTokens(t *testing.T) {
+	table, err := ReadTokens(strings.NewReader(`
+# token subject roles...
+t1 khoi
+t2 root admin editor
+`))
+	if err != nil {
+		t.Fatalf("ReadTokens() had unexpected error: %v", err)
+	}
+	if got := len(table); got != 2 {
+		t.Fatalf("ReadTokens() got %d tokens, want 2", got)
+	}
+	if id := table["t1"]; id.Subject != "khoi" || id.IsAdmin() {
+		t.Errorf("ReadTokens() got %+v for t1, want non-admin khoi", id)
+	}
+	if id := table["t2"]; id.Subject != "root" || !id.IsAdmin() {
+		t.Errorf("ReadTokens() got %+v for t2, want admin root", id)
+	}
+
+	for _, bad := range []string{"lonely", "t1 a\nt1 b"} {
+		if _, err := ReadTokens(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadTokens(%q) got no error", bad)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(TokenTable{"t1": {Subject: "khoi"}})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, ok := FromContext(ctx)
+		if !ok {
+			return "", nil
+		}
+		return id.Subject, nil
+	}
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		wantCode codes.Code
+		want     interface{}
+	}{
+		{"anonymous", nil, codes.OK, ""},
+		{"valid token", metadata.Pairs("authorization", "Bearer t1"), codes.OK, "khoi"},
+		{"unknown token", metadata.Pairs("authorization", "Bearer t2"), codes.Unauthenticated, nil},
+		{"not bearer", metadata.Pairs("authorization", "Basic t1"), codes.Unauthenticated, nil},
+	}
+
+	for _, tt := range tests {
+		ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+		got, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		if code := status.Code(err); code != tt.wantCode {
+			t.Errorf("interceptor(%s) got code %v, want %v", tt.name, code, tt.wantCode)
+		}
+		if got != tt.want {
+			t.Errorf("interceptor(%s) got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const bearerPrefix = "Bearer "
+
+// errorDomain is the domain of the ErrorInfo details of authentication errors.
+const errorDomain = "auth.grpc-go-course"
+
+// authenticate returns ctx with the identity of the bearer token found in its
+// incoming metadata. Calls without an authorization header stay anonymous, so
+// handlers decide which calls need an identity; a bad token is rejected.
+func authenticate(ctx context.Context, a Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, nil
+	}
+	if len(values) > 1 || !strings.HasPrefix(values[0], bearerPrefix) {
+		return nil, rpcerr.New(
+			codes.Unauthenticated,
+			"authorization must be a single bearer token",
+			rpcerr.Info("MALFORMED_AUTHORIZATION", errorDomain, nil),
+		)
+	}
+
+	id, err := a.Authenticate(ctx, strings.TrimPrefix(values[0], bearerPrefix))
+	if err == ErrInvalidToken {
+		return nil, rpcerr.New(
+			codes.Unauthenticated,
+			"invalid bearer token",
+			rpcerr.Info("INVALID_TOKEN", errorDomain, nil),
+		)
+	}
+	if err != nil {
+		return nil, rpcerr.New(
+			codes.Internal,
+			fmt.Sprintf("cannot check bearer token: %v", err),
+			rpcerr.Info("INTERNAL", errorDomain, nil),
+		)
+	}
+
+	return NewContext(ctx, id), nil
+}
+
+// UnaryServerInterceptor authenticates the bearer token of unary calls.
+func UnaryServerInterceptor(a Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, a)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates the bearer token of streaming calls.
+func StreamServerInterceptor(a Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), a)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identityStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
+
+type bearerToken string
+
+// BearerToken returns credentials that send token with every call, for use
+// with grpc.WithPerRPCCredentials.
+func BearerToken(token string) credentials.PerRPCCredentials {
+	return bearerToken(token)
+}
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": bearerPrefix + string(t)}, nil
+}
+
+// RequireTransportSecurity allows tokens over plaintext connections, like the
+// ones the demo servers listen on.
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
@@ -2,106 +2,138 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"io"
 	"log"
+	"os"
+	"time"
 
+	"github.com/mirageruler/grpc-go-course/blog/auth"
 	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/metrics"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+	"github.com/mirageruler/grpc-go-course/tracing"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
-func main() {
-	fmt.Println("Blog Client")
-	fmt.Println("----------------------------------------------------------------------------------------------------------------------------------------")
-
-	opts := grpc.WithTransportCredentials(insecure.NewCredentials())
-
-	conn, err := grpc.Dial("localhost:50051", opts)
-	if err != nil {
-		log.Fatalf("could not connect: %v", err)
-	}
-	defer conn.Close()
-
-	c := blogpb.NewBlogServiceClient(conn)
+// command is a subcommand of the blog client. run gets the arguments after
+// the subcommand name and writes its results with out.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, c blogpb.BlogServiceClient, out *printer, args []string) error
+}
 
-	// create a blog
-	fmt.Println("Creating the blog...")
+var commands = []command{
+	{"create", "create -title=TITLE (-content=TEXT | -content-file=FILE) [-tags=a,b] [-author=ID]", createBlog},
+	{"get", "get [-show-deleted] [-render=raw|html|plain_text] ID", getBlog},
+	{"update", "update [-title=TITLE] [-content=TEXT | -content-file=FILE] [-tags=a,b] [-author=ID] ID", updateBlog},
+	{"delete", "delete [-revision=N] ID", deleteBlog},
+	{"list", "list [-author=ID] [-tags=a,b] [-trash=hide|only|with] [-newest] [-page-size=N [-page-token=TOKEN]]", listBlogs},
+	{"search", "search [-limit=N] WORDS...", searchBlogs},
+	{"export", "export [-format=jsonl|binary] [-o=FILE] [-trashed] [-author=ID]", exportBlogs},
+	{"import", "import [-format=jsonl|binary] [FILE]", importBlogs},
+}
 
-	blog := &blogpb.Blog{
-		AuthorId: "Khoi",
-		Title:    "My first blog",
-		Content:  "Content of the first blog",
+func main() {
+	addr := flag.String("addr", "localhost:50051", "address of the blog server")
+	useTLS := flag.Bool("tls", false, "connect with TLS")
+	caFile := flag.String("ca-file", "ssl/ca.crt", "certificate authority to trust with -tls")
+	serverName := flag.String("server-name", "", "name to verify the server certificate against with -tls, the host of -addr if empty")
+	timeout := flag.Duration("timeout", 30*time.Second, "deadline for the whole command, 0 for none")
+	token := flag.String("token", "", "bearer token identifying the author, see the server's -tokens file")
+	output := flag.String("output", outputTable, "output format: table, json or yaml")
+	traceExporter := flag.String("trace-exporter", "none", "exporter of the spans of the command: none, stdout or file")
+	traceFile := flag.String("trace-file", "", "file the file exporter appends spans to as JSON lines")
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, ok := findCommand(flag.Arg(0))
+	if !ok {
+		usage()
+		os.Exit(2)
 	}
-
-	createBlogRes, err := c.CreateBlog(context.Background(), &blogpb.CreateBlogRequest{Blog: blog})
+	out, err := newPrinter(os.Stdout, *output)
 	if err != nil {
-		log.Fatalf("Unexpected error: %v", err)
-	}
-
-	fmt.Printf("Blog has been created: %v\n", createBlogRes)
-	blogId := createBlogRes.GetBlog().GetId()
-
-	// ----------------------------------------------------------------------------------------------------
-	// read a blog
-	fmt.Println("Reading the blog...")
-
-	// not found
-	if _, err = c.ReadBlog(context.Background(), &blogpb.ReadBlogRequest{BlogId: "5bdc29e661b75adcac496cf4"}); err != nil {
-		fmt.Printf("Error happened while reading: %v\n", err)
+		log.Fatal(err)
 	}
 
-	// found
-	readBlogReq := &blogpb.ReadBlogRequest{BlogId: blogId}
-	readBlogRes, err := c.ReadBlog(context.Background(), readBlogReq)
-	if err != nil {
-		fmt.Printf("Error happened while reading: %v\n", err)
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if *useTLS {
+		creds, err := credentials.NewClientTLSFromFile(*caFile, *serverName)
+		if err != nil {
+			log.Fatalf("error while loading CA trust certificate: %v", err)
+		}
+		opts[0] = grpc.WithTransportCredentials(creds)
 	}
-
-	fmt.Printf("Blog was read: %v\n", readBlogRes)
-
-	// ----------------------------------------------------------------------------------------------------
-	// update a blog
-	fmt.Println("Updating the blog...")
-
-	newBlog := &blogpb.Blog{
-		Id:       blogId,
-		AuthorId: "Changed Author",
-		Title:    "My First Blog (edited)",
-		Content:  "Content of my first blog, with new additions",
+	if *token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(auth.BearerToken(*token)))
 	}
-	updatedBlogRes, err := c.UpdateBlog(context.Background(), &blogpb.UpdateBlogRequest{Blog: newBlog})
+	exporter, err := tracing.NewExporter(*traceExporter, *traceFile)
 	if err != nil {
-		fmt.Printf("Error happened while updating: %v\n", err)
+		log.Fatal(err)
 	}
-
-	fmt.Printf("Updated blog : %v\n", updatedBlogRes)
-
-	// ----------------------------------------------------------------------------------------------------
-	// delete a blog
-	deletedRes, err := c.DeleteBlog(context.Background(), &blogpb.DeleteBlogRequest{BlogId: blogId})
+	// even without an exporter, the calls carry the trace of the command to
+	// the server
+	tracing.DefaultTracer.Configure("blog_client", exporter)
+	clientMetrics := metrics.NewClientMetrics(metrics.DefaultRegistry)
+	opts = append(opts,
+		grpc.WithChainUnaryInterceptor(
+			tracing.UnaryClientInterceptor(tracing.DefaultTracer),
+			metrics.UnaryClientInterceptor(clientMetrics),
+		),
+		grpc.WithChainStreamInterceptor(
+			tracing.StreamClientInterceptor(tracing.DefaultTracer),
+			metrics.StreamClientInterceptor(clientMetrics),
+		),
+	)
+
+	conn, err := grpc.Dial(*addr, opts...)
 	if err != nil {
-		fmt.Printf("Error happened while deleting: %v\n", err)
+		log.Fatalf("could not connect: %v", err)
 	}
+	defer conn.Close()
 
-	fmt.Printf("Blog was deleted: %v \n", deletedRes)
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
 
-	// ----------------------------------------------------------------------------------------------------
-	// listing blogs
-	stream, err := c.ListBlog(context.Background(), &blogpb.ListBlogRequest{})
+	// the calls of the command are spans of one trace
+	ctx, span := tracing.DefaultTracer.Start(ctx, "blog_client "+cmd.name, tracing.Internal)
+	err = cmd.run(ctx, blogpb.NewBlogServiceClient(conn), out, flag.Args()[1:])
+	span.End(err)
+	tracing.DefaultTracer.Close()
 	if err != nil {
-		log.Fatalf("error while calling ListBlog RPC: %v", err)
+		conn.Close()
+		if _, ok := status.FromError(err); ok {
+			log.Fatalf("%s failed: %v", cmd.name, rpcerr.Describe(err))
+		}
+		log.Fatalf("%s failed: %v", cmd.name, err)
 	}
+}
 
-	for {
-		res, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatalf("Something happened: %v", err)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
 		}
-		fmt.Println(res.GetBlog())
 	}
+	return command{}, false
+}
+
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: %s [flags] COMMAND [command flags] [args]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\n", cmd.usage)
+	}
+	fmt.Fprintf(w, "\nFlags:\n")
+	flag.PrintDefaults()
 }
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+)
+
+// maxUpdateAttempts is how often update retries when another client changes
+// the blog between its read and its write.
+const maxUpdateAttempts = 3
+
+// blogFlags are the flags of the commands that write a blog.
+type blogFlags struct {
+	fs          *flag.FlagSet
+	title       string
+	content     string
+	contentFile string
+	tags        string
+	author      string
+}
+
+func newBlogFlags(name string) *blogFlags {
+	f := &blogFlags{fs: flag.NewFlagSet(name, flag.ExitOnError)}
+	f.fs.StringVar(&f.title, "title", "", "title of the blog")
+	f.fs.StringVar(&f.content, "content", "", "Markdown content of the blog")
+	f.fs.StringVar(&f.contentFile, "content-file", "", "file to read the content from, - for standard input")
+	f.fs.StringVar(&f.tags, "tags", "", "comma separated tags of the blog")
+	f.fs.StringVar(&f.author, "author", "", "author of the blog, only admins may set it")
+	return f
+}
+
+// parse parses args and reads -content-file up front, so that apply can run
+// again when an update is retried.
+func (f *blogFlags) parse(args []string) error {
+	f.fs.Parse(args)
+	if f.contentFile == "" {
+		return nil
+	}
+	content, err := readContent(f.contentFile)
+	if err != nil {
+		return err
+	}
+	f.content = content
+	return nil
+}
+
+// apply copies the flags given on the command line to blog, leaving the
+// fields of the others as they are.
+func (f *blogFlags) apply(blog *blogpb.Blog) {
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "title":
+			blog.Title = f.title
+		case "content", "content-file":
+			blog.Content = f.content
+		case "tags":
+			blog.Tags = splitList(f.tags)
+		case "author":
+			blog.AuthorId = f.author
+		}
+	})
+}
+
+func readContent(path string) (string, error) {
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(path)
+	}
+	return string(b), err
+}
+
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseEnum looks up the value of an enum flag by its name in the proto,
+// ignoring case.
+func parseEnum(flagName, value string, values map[string]int32) (int32, error) {
+	v, ok := values[strings.ToUpper(value)]
+	if !ok {
+		return 0, fmt.Errorf("unknown -%s %q", flagName, value)
+	}
+	return v, nil
+}
+
+// blogID returns the single blog ID argument of a command.
+func blogID(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s needs exactly one blog ID", fs.Name())
+	}
+	return fs.Arg(0), nil
+}
+
+func createBlog(ctx context.Context, c blogpb.BlogServiceClient, out *printer, args []string) error {
+	f := newBlogFlags("create")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	blog := &blogpb.Blog{}
+	f.apply(blog)
+
+	res, err := c.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: blog})
+	if err != nil {
+		return err
+	}
+	return out.blog(res.GetBlog())
+}
+
+func getBlog(ctx context.Context, c blogpb.BlogServiceClient, out *printer, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	showDeleted := fs.Bool("show-deleted", false, "also get the blog if it is in the trash")
+	render := fs.String("render", "raw", "format of the content: raw, html or plain_text")
+	fs.Parse(args)
+
+	id, err := blogID(fs)
+	if err != nil {
+		return err
+	}
+	format, err := parseEnum("render", *render, blogpb.RenderFormat_value)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.ReadBlog(ctx, &blogpb.ReadBlogRequest{
+		BlogId:       id,
+		ShowDeleted:  *showDeleted,
+		RenderFormat: blogpb.RenderFormat(format),
+	})
+	if err != nil {
+		return err
+	}
+	return out.blog(res.GetBlog())
+}
+
+func updateBlog(ctx context.Context, c blogpb.BlogServiceClient, out *printer, args []string) error {
+	f := newBlogFlags("update")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	id, err := blogID(f.fs)
+	if err != nil {
+		return err
+	}
+
+	blog, err := updateBlogWithRetry(ctx, c, id, maxUpdateAttempts, f.apply)
+	if err != nil {
+		return err
+	}
+	return out.blog(blog)
+}
+
+func deleteBlog(ctx context.Context, c blogpb.BlogServiceClient, out *printer, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	revision := fs.Int64("revision", 0, "only delete the blog if it is still at this revision")
+	fs.Parse(args)
+
+	id, err := blogID(fs)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: id, ExpectedRevision: *revision})
+	if err != nil {
+		return err
+	}
+	if out.format == outputTable {
+		_, err = fmt.Fprintf(out.w, "Moved blog %s to the trash\n", res.GetBlogId())
+		return err
+	}
+	return out.message(res)
+}
+
+func listBlogs(ctx context.Context, c blogpb.BlogServiceClient, out *printer, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	author := fs.String("author", "", "only list the blogs of this author")
+	tags := fs.String("tags", "", "only list blogs with all of these comma separated tags")
+	trash := fs.String("trash", "hide", "blogs in the trash: hide, only or with")
+	newest := fs.Bool("newest", false, "list the newest blogs first")
+	pageSize := fs.Int("page-size", 0, "list one page of this many blogs instead of all of them")
+	pageToken := fs.String("page-token", "", "next page token printed by the previous page")
+	fs.Parse(args)
+
+	trashFilter, err := parseEnum("trash", *trash+"_trashed", blogpb.ListBlogRequest_Trash_value)
+	if err != nil {
+		return err
+	}
+	req := &blogpb.ListBlogRequest{
+		AuthorId:  *author,
+		Tags:      splitList(*tags),
+		Trash:     blogpb.ListBlogRequest_Trash(trashFilter),
+		PageSize:  int32(*pageSize),
+		PageToken: *pageToken,
+	}
+	if *newest {
+		req.SortOrder = blogpb.ListBlogRequest_NEWEST_FIRST
+	}
+
+	if *pageSize > 0 {
+		res, err := c.ListBlogsPage(ctx, req)
+		if err != nil {
+			return err
+		}
+		if res.GetNextPageToken() != "" {
+			fmt.Fprintf(os.Stderr, "Next page: -page-token=%s\n", res.GetNextPageToken())
+		}
+		return out.blogs(res.GetBlogs())
+	}
+	if *pageToken != "" {
+		return errors.New("-page-token needs -page-size")
+	}
+
+	stream, err := c.ListBlog(ctx, req)
+	if err != nil {
+		return err
+	}
+	var blogs []*blogpb.Blog
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		blogs = append(blogs, res.GetBlog())
+	}
+	return out.blogs(blogs)
+}
+
+func searchBlogs(ctx context.Context, c blogpb.BlogServiceClient, out *printer, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := fs.Int("limit", 0, "most results to return, 0 for the server default")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return errors.New("search needs the words to look for")
+	}
+
+	res, err := c.SearchBlogs(ctx, &blogpb.SearchBlogsRequest{
+		Query: strings.Join(fs.Args(), " "),
+		Limit: int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+	return out.results(res.GetResults())
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the -output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// printer writes the results of commands in one output format. JSON and YAML
+// use the proto field names, so they can be fed to other tools.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return &printer{w: w, format: format}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, want %s, %s or %s", format, outputTable, outputJSON, outputYAML)
+}
+
+// blog writes a single blog, with its content when printed as a table.
+func (p *printer) blog(blog *blogpb.Blog) error {
+	if p.format != outputTable {
+		return p.message(blog)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", blog.GetId())
+	fmt.Fprintf(tw, "AUTHOR:\t%s\n", blog.GetAuthorId())
+	fmt.Fprintf(tw, "TITLE:\t%s\n", blog.GetTitle())
+	fmt.Fprintf(tw, "TAGS:\t%s\n", strings.Join(blog.GetTags(), ", "))
+	fmt.Fprintf(tw, "REVISION:\t%d\n", blog.GetRevision())
+	fmt.Fprintf(tw, "CREATED:\t%s\n", formatTime(blog.GetCreatedAt()))
+	fmt.Fprintf(tw, "UPDATED:\t%s\n", formatTime(blog.GetUpdatedAt()))
+	if blog.GetDeletedAt() != nil {
+		fmt.Fprintf(tw, "DELETED:\t%s\n", formatTime(blog.GetDeletedAt()))
+	}
+	fmt.Fprintf(tw, "READING TIME:\t%d min (%d words)\n", blog.GetReadingTimeMinutes(), blog.GetWordCount())
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(p.w, "\n%s\n", blog.GetContent())
+	return err
+}
+
+// blogs writes a list of blogs, one row per blog when printed as a table.
+func (p *printer) blogs(blogs []*blogpb.Blog) error {
+	if p.format != outputTable {
+		msgs := make([]proto.Message, len(blogs))
+		for i, blog := range blogs {
+			msgs[i] = blog
+		}
+		return p.messages(msgs)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAUTHOR\tTITLE\tTAGS\tREVISION\tUPDATED")
+	for _, blog := range blogs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			blog.GetId(), blog.GetAuthorId(), blog.GetTitle(), strings.Join(blog.GetTags(), ","), blog.GetRevision(), formatTime(blog.GetUpdatedAt()))
+	}
+	return tw.Flush()
+}
+
+// results writes search results, best match first.
+func (p *printer) results(results []*blogpb.SearchResult) error {
+	if p.format != outputTable {
+		msgs := make([]proto.Message, len(results))
+		for i, result := range results {
+			msgs[i] = result
+		}
+		return p.messages(msgs)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCORE\tID\tAUTHOR\tTITLE")
+	for _, result := range results {
+		blog := result.GetBlog()
+		fmt.Fprintf(tw, "%.2f\t%s\t%s\t%s\n", result.GetScore(), blog.GetId(), blog.GetAuthorId(), blog.GetTitle())
+	}
+	return tw.Flush()
+}
+
+// message writes a response that has no table layout of its own as JSON or
+// YAML, or as the text format of protobuf for tables.
+func (p *printer) message(msg proto.Message) error {
+	if p.format == outputTable {
+		_, err := fmt.Fprintln(p.w, msg)
+		return err
+	}
+	v, err := toJSONValue(msg)
+	if err != nil {
+		return err
+	}
+	return p.encode(v)
+}
+
+// messages writes msgs as a JSON array or a YAML sequence.
+func (p *printer) messages(msgs []proto.Message) error {
+	vs := make([]interface{}, len(msgs))
+	for i, msg := range msgs {
+		v, err := toJSONValue(msg)
+		if err != nil {
+			return err
+		}
+		vs[i] = v
+	}
+	return p.encode(vs)
+}
+
+func (p *printer) encode(v interface{}) error {
+	if p.format == outputYAML {
+		enc := yaml.NewEncoder(p.w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// toJSONValue converts msg to the generic value of its protojson encoding,
+// which both encoding/json and yaml can write.
+func toJSONValue(msg proto.Message) (interface{}, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func formatTime(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return "-"
+	}
+	return ts.AsTime().Local().Format(time.RFC3339)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+)
+
+func TestPrinter_Blogs(t *testing.T) {
+	blogs := []*blogpb.Blog{
+		{Id: "5bdc29e661b75adcac496cf4", AuthorId: "khoi", Title: "first", Tags: []string{"go", "grpc"}, Revision: 2},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{outputTable, "ID                        AUTHOR  TITLE  TAGS     REVISION  UPDATED\n" +
+			"5bdc29e661b75adcac496cf4  khoi    first  go,grpc  2         -\n"},
+		{outputJSON, "[\n  {\n    \"author_id\": \"khoi\",\n    \"id\": \"5bdc29e661b75adcac496cf4\",\n    \"revision\": \"2\",\n" +
+			"    \"tags\": [\n      \"go\",\n      \"grpc\"\n    ],\n    \"title\": \"first\"\n  }\n]\n"},
+		{outputYAML, "- author_id: khoi\n  id: 5bdc29e661b75adcac496cf4\n  revision: \"2\"\n  tags:\n    - go\n    - grpc\n  title: first\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		out, err := newPrinter(&buf, tt.format)
+		if err != nil {
+			t.Fatalf("newPrinter(%s) had unexpected error: %v", tt.format, err)
+		}
+		if err := out.blogs(blogs); err != nil {
+			t.Fatalf("blogs(%s) had unexpected error: %v", tt.format, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("blogs(%s) got\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestPrinter_BlogTable(t *testing.T) {
+	var buf bytes.Buffer
+	out, _ := newPrinter(&buf, outputTable)
+	if err := out.blog(&blogpb.Blog{Id: "5bdc29e661b75adcac496cf4", Title: "first", Content: "# Hello"}); err != nil {
+		t.Fatalf("blog() had unexpected error: %v", err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "ID:  ") || !strings.HasSuffix(got, "\n\n# Hello\n") {
+		t.Errorf("blog() got\n%s\nwant the fields followed by the content", got)
+	}
+}
+
+func TestNewPrinter_UnknownFormat(t *testing.T) {
+	if _, err := newPrinter(&bytes.Buffer{}, "xml"); err == nil {
+		t.Errorf("newPrinter(xml) got no error")
+	}
+}
+
+func TestBlogFlags_Apply(t *testing.T) {
+	f := newBlogFlags("update")
+	if err := f.parse([]string{"-title=new", "-tags=go, ,grpc", "5bdc29e661b75adcac496cf4"}); err != nil {
+		t.Fatalf("parse() had unexpected error: %v", err)
+	}
+
+	blog := &blogpb.Blog{Title: "old", Content: "kept", Tags: []string{"old"}}
+	f.apply(blog)
+	if blog.GetTitle() != "new" || blog.GetContent() != "kept" {
+		t.Errorf("apply() got title %q and content %q, want new and kept", blog.GetTitle(), blog.GetContent())
+	}
+	if want := []string{"go", "grpc"}; !reflect.DeepEqual(blog.GetTags(), want) {
+		t.Errorf("apply() got tags %q, want %q", blog.GetTags(), want)
+	}
+	if id, err := blogID(f.fs); err != nil || id != "5bdc29e661b75adcac496cf4" {
+		t.Errorf("blogID() got %q, %v, want the ID argument", id, err)
+	}
+}
+
+func TestParseEnum(t *testing.T) {
+	if got, err := parseEnum("render", "plain_text", blogpb.RenderFormat_value); err != nil || got != int32(blogpb.RenderFormat_PLAIN_TEXT) {
+		t.Errorf("parseEnum(plain_text) got %v, %v, want %v", got, err, blogpb.RenderFormat_PLAIN_TEXT)
+	}
+	if _, err := parseEnum("render", "pdf", blogpb.RenderFormat_value); err == nil {
+		t.Errorf("parseEnum(pdf) got no error")
+	}
+}
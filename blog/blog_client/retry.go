@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryBackoff is the pause before the second attempt of a read-modify-write
+// loop; it grows linearly with every further attempt.
+var retryBackoff = 50 * time.Millisecond
+
+// updateBlogWithRetry reads the blog with the given ID, applies mutate to it and
+// sends an update that only succeeds if nobody changed the blog in between.
+// When the server reports a conflict (ABORTED) the whole loop starts over,
+// up to maxAttempts times.
+func updateBlogWithRetry(ctx context.Context, c blogpb.BlogServiceClient, blogID string, maxAttempts int, mutate func(*blogpb.Blog)) (*blogpb.Blog, error) {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * retryBackoff):
+			}
+		}
+
+		var readRes *blogpb.ReadBlogResponse
+		readRes, err = c.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: blogID})
+		if err != nil {
+			return nil, err
+		}
+
+		blog := readRes.GetBlog()
+		mutate(blog)
+
+		var updateRes *blogpb.UpdateBlogResponse
+		updateRes, err = c.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{
+			Blog:             blog,
+			ExpectedRevision: readRes.GetBlog().GetRevision(),
+		})
+		if err == nil {
+			return updateRes.GetBlog(), nil
+		}
+		if status.Code(err) != codes.Aborted {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// conflictingClient simulates another editor updating the blog between our
+// read and our write for the first `conflicts` update attempts.
+type conflictingClient struct {
+	blogpb.BlogServiceClient
+
+	blog      *blogpb.Blog
+	conflicts int
+}
+
+func (c *conflictingClient) ReadBlog(ctx context.Context, in *blogpb.ReadBlogRequest, opts ...grpc.CallOption) (*blogpb.ReadBlogResponse, error) {
+	return &blogpb.ReadBlogResponse{Blog: proto.Clone(c.blog).(*blogpb.Blog)}, nil
+}
+
+func (c *conflictingClient) UpdateBlog(ctx context.Context, in *blogpb.UpdateBlogRequest, opts ...grpc.CallOption) (*blogpb.UpdateBlogResponse, error) {
+	if c.conflicts > 0 {
+		c.conflicts--
+		c.blog.Revision++
+	}
+	if in.GetExpectedRevision() != c.blog.GetRevision() {
+		return nil, status.Error(codes.Aborted, "revision mismatch")
+	}
+
+	c.blog = proto.Clone(in.GetBlog()).(*blogpb.Blog)
+	c.blog.Revision++
+	return &blogpb.UpdateBlogResponse{Blog: c.blog}, nil
+}
+
+func TestUpdateBlogWithRetry(t *testing.T) {
+	retryBackoff = 0
+
+	c := &conflictingClient{blog: &blogpb.Blog{Id: "1", Title: "v1", Revision: 1}, conflicts: 2}
+	got, err := updateBlogWithRetry(context.Background(), c, "1", 3, func(b *blogpb.Blog) {
+		b.Title = "edited"
+	})
+	if err != nil {
+		t.Fatalf("updateBlogWithRetry() had unexpected error: %v", err)
+	}
+	if got.GetTitle() != "edited" || got.GetRevision() != 4 {
+		t.Errorf("updateBlogWithRetry() got title %q at revision %d, want %q at revision 4", got.GetTitle(), got.GetRevision(), "edited")
+	}
+}
+
+func TestUpdateBlogWithRetry_GivesUp(t *testing.T) {
+	retryBackoff = 0
+
+	c := &conflictingClient{blog: &blogpb.Blog{Id: "1", Revision: 1}, conflicts: 5}
+	_, err := updateBlogWithRetry(context.Background(), c, "1", 3, func(b *blogpb.Blog) {})
+	if got := status.Code(err); got != codes.Aborted {
+		t.Errorf("updateBlogWithRetry() got code %v, want %v", got, codes.Aborted)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Formats of export files.
+const (
+	// formatJSONL writes one protojson encoded blog per line.
+	formatJSONL = "jsonl"
+	// formatBinary writes binary protobuf blogs, each preceded by its size
+	// as a varint, like Java's writeDelimitedTo.
+	formatBinary = "binary"
+)
+
+// maxRecordSize bounds the size of a binary blog, so a corrupt file does not
+// make the reader allocate gigabytes.
+const maxRecordSize = 4 << 20
+
+// blogWriter writes one blog to an export file.
+type blogWriter func(blog *blogpb.Blog) error
+
+// blogReader reads the next blog of an export file, returning io.EOF after
+// the last one.
+type blogReader func() (*blogpb.Blog, error)
+
+func newBlogWriter(w io.Writer, format string) (blogWriter, error) {
+	switch format {
+	case formatJSONL:
+		return func(blog *blogpb.Blog) error {
+			b, err := protojson.Marshal(blog)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(append(b, '\n'))
+			return err
+		}, nil
+	case formatBinary:
+		return func(blog *blogpb.Blog) error {
+			b, err := proto.Marshal(blog)
+			if err != nil {
+				return err
+			}
+			size := make([]byte, binary.MaxVarintLen64)
+			if _, err := w.Write(size[:binary.PutUvarint(size, uint64(len(b)))]); err != nil {
+				return err
+			}
+			_, err = w.Write(b)
+			return err
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q, want %s or %s", format, formatJSONL, formatBinary)
+}
+
+func newBlogReader(r io.Reader, format string) (blogReader, error) {
+	br := bufio.NewReader(r)
+	switch format {
+	case formatJSONL:
+		line := 0
+		return func() (*blogpb.Blog, error) {
+			for {
+				b, err := br.ReadBytes('\n')
+				if err != nil && (err != io.EOF || len(b) == 0) {
+					return nil, err
+				}
+				line++
+				if b = bytes.TrimSpace(b); len(b) == 0 {
+					continue
+				}
+				blog := &blogpb.Blog{}
+				if err := protojson.Unmarshal(b, blog); err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+				return blog, nil
+			}
+		}, nil
+	case formatBinary:
+		return func() (*blogpb.Blog, error) {
+			size, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, err
+			}
+			if size > maxRecordSize {
+				return nil, fmt.Errorf("blog of %d bytes is larger than %d bytes", size, maxRecordSize)
+			}
+			b := make([]byte, size)
+			if _, err := io.ReadFull(br, b); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			blog := &blogpb.Blog{}
+			if err := proto.Unmarshal(b, blog); err != nil {
+				return nil, err
+			}
+			return blog, nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q, want %s or %s", format, formatJSONL, formatBinary)
+}
+
+// exportBlogs runs the export subcommand, which writes the blogs to a file.
+func exportBlogs(ctx context.Context, c blogpb.BlogServiceClient, _ *printer, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", formatJSONL, "file format, jsonl or binary")
+	path := fs.String("o", "", "file to write, standard output if empty")
+	trashed := fs.Bool("trashed", false, "also export the blogs in the trash")
+	author := fs.String("author", "", "only export the blogs of this author")
+	fs.Parse(args)
+
+	w := io.Writer(os.Stdout)
+	if *path != "" {
+		f, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	write, err := newBlogWriter(bw, *format)
+	if err != nil {
+		return err
+	}
+
+	req := &blogpb.ExportBlogsRequest{AuthorId: *author}
+	if *trashed {
+		req.Trash = blogpb.ListBlogRequest_WITH_TRASHED
+	}
+	stream, err := c.ExportBlogs(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := write(res.GetBlog()); err != nil {
+			return err
+		}
+		n++
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d blogs\n", n)
+	return nil
+}
+
+// importBlogs runs the import subcommand, which sends the blogs of a file to
+// the server. Blogs keep their IDs, so the same file can be imported again.
+func importBlogs(ctx context.Context, c blogpb.BlogServiceClient, out *printer, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", formatJSONL, "file format, jsonl or binary")
+	fs.Parse(args)
+
+	r := io.Reader(os.Stdin)
+	if path := fs.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	read, err := newBlogReader(r, *format)
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.ImportBlogs(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		blog, err := read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&blogpb.ImportBlogsRequest{Blog: blog}); err != nil {
+			// the server ended the call, CloseAndRecv returns its error
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+
+	return out.message(res)
+}
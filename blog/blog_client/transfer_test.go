@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestBlogWriterReader(t *testing.T) {
+	blogs := []*blogpb.Blog{
+		{Id: "5bdc29e661b75adcac496cf4", AuthorId: "khoi", Title: "first", Content: "line 1\nline 2", Revision: 3, CreatedAt: timestamppb.Now()},
+		{Id: "5bdc29e661b75adcac496cf5", Title: "second", Tags: []string{"go", "grpc"}},
+	}
+
+	for _, format := range []string{formatJSONL, formatBinary} {
+		var buf bytes.Buffer
+		write, err := newBlogWriter(&buf, format)
+		if err != nil {
+			t.Fatalf("newBlogWriter(%s) had unexpected error: %v", format, err)
+		}
+		for _, blog := range blogs {
+			if err := write(blog); err != nil {
+				t.Fatalf("write(%s) had unexpected error: %v", format, err)
+			}
+		}
+
+		read, err := newBlogReader(&buf, format)
+		if err != nil {
+			t.Fatalf("newBlogReader(%s) had unexpected error: %v", format, err)
+		}
+		for _, want := range blogs {
+			got, err := read()
+			if err != nil {
+				t.Fatalf("read(%s) had unexpected error: %v", format, err)
+			}
+			if !proto.Equal(got, want) {
+				t.Errorf("read(%s) got %v, want %v", format, got, want)
+			}
+		}
+		if _, err := read(); err != io.EOF {
+			t.Errorf("read(%s) after the last blog got error %v, want %v", format, err, io.EOF)
+		}
+	}
+}
+
+func TestBlogReader_JSONL(t *testing.T) {
+	read, err := newBlogReader(strings.NewReader("\n{\"title\": \"a\"}\n\n{\"title\": \"b\"}\n{oops"), formatJSONL)
+	if err != nil {
+		t.Fatalf("newBlogReader() had unexpected error: %v", err)
+	}
+	for _, want := range []string{"a", "b"} {
+		blog, err := read()
+		if err != nil || blog.GetTitle() != want {
+			t.Fatalf("read() got %v, %v, want blog %s", blog, err, want)
+		}
+	}
+	if _, err := read(); err == nil || !strings.HasPrefix(err.Error(), "line 5:") {
+		t.Errorf("read() of a bad line got error %v, want one on line 5", err)
+	}
+}
+
+func TestBlogReader_TruncatedBinary(t *testing.T) {
+	read, err := newBlogReader(bytes.NewReader([]byte{10, 1, 2}), formatBinary)
+	if err != nil {
+		t.Fatalf("newBlogReader() had unexpected error: %v", err)
+	}
+	if _, err := read(); err != io.ErrUnexpectedEOF {
+		t.Errorf("read() of a truncated blog got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestNewBlogWriter_UnknownFormat(t *testing.T) {
+	if _, err := newBlogWriter(io.Discard, "csv"); err == nil {
+		t.Errorf("newBlogWriter(csv) got no error")
+	}
+}
@@ -2,259 +2,86 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
-	"time"
 
-	"github.com/mirageruler/grpc-go-course/blog/blogpb"
-	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogservice"
+	"github.com/mirageruler/grpc-go-course/blog/validate"
+	"github.com/mirageruler/grpc-go-course/config"
+	"github.com/mirageruler/grpc-go-course/healthcheck"
+	"github.com/mirageruler/grpc-go-course/tracing"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
-	"google.golang.org/grpc/status"
 )
 
-type server struct{}
-
-func (*server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest) (*blogpb.CreateBlogResponse, error) {
-	fmt.Println("Creating a blog...")
-	blog := req.GetBlog()
-
-	data := models.BlogItem{
-		AuthorID: blog.GetAuthorId(),
-		Title:    blog.GetTitle(),
-		Content:  blog.GetContent(),
-	}
-
-	result, err := collection.InsertOne(context.Background(), data)
-	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Internal error: %v", err),
-		)
-	}
-
-	oid, ok := result.InsertedID.(primitive.ObjectID)
-	if !ok {
-		return nil, status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Cannot convert to primitive.ObjectID: %v", err),
-		)
-	}
-
-	return &blogpb.CreateBlogResponse{
-		Blog: &blogpb.Blog{
-			Id:       oid.Hex(),
-			AuthorId: blog.GetAuthorId(),
-			Title:    blog.GetTitle(),
-			Content:  blog.GetContent(),
-		},
-	}, nil
-}
-
-func (*server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*blogpb.ReadBlogResponse, error) {
-	fmt.Println("Finding the blog...")
-
-	blogID := req.GetBlogId()
-
-	oid, err := primitive.ObjectIDFromHex(blogID)
-	if err != nil {
-		if err != nil {
-			return nil, status.Errorf(
-				codes.InvalidArgument,
-				fmt.Sprintf("Cannot parse blogID :%v", err),
-			)
-		}
-	}
-
-	// create an empty BlogItem
-	data := &models.BlogItem{}
-	filter := bson.M{"_id": oid}
-
-	result := collection.FindOne(context.Background(), filter)
-
-	if err = result.Decode(data); err != nil {
-		return nil, status.Errorf(
-			codes.NotFound,
-			fmt.Sprintf("Cannot find blog with specified ID: %v", oid),
-			fmt.Sprintf("Error: %v", err),
-		)
-	}
-
-	if data == nil {
-		return nil, status.Errorf(
-			codes.NotFound,
-			fmt.Sprintf("Cannot find blog with specified ID: %v", oid),
-		)
-	}
-
-	return &blogpb.ReadBlogResponse{
-		Blog: dataToBlogPb(data),
-	}, nil
-}
-
-func dataToBlogPb(data *models.BlogItem) *blogpb.Blog {
-	return &blogpb.Blog{
-		Id:       data.ID.Hex(),
-		AuthorId: data.AuthorID,
-		Title:    data.Title,
-		Content:  data.Content,
-	}
-}
+func main() {
+	// if we crash the go code, we  get the file name and the line number
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
-func (*server) UpdateBlog(ctx context.Context, req *blogpb.UpdateBlogRequest) (*blogpb.UpdateBlogResponse, error) {
-	fmt.Println("Updating the blog...")
+	defaults := config.Defaults()
+	defaults.Database = blogservice.DefaultDatabase()
+	defaults.Blog = blogservice.DefaultBlog()
+	loader := config.Register(flag.CommandLine, "BLOG", defaults)
+	flag.Parse()
 
-	blog := req.GetBlog()
-	oid, err := primitive.ObjectIDFromHex(blog.GetId())
+	cfg, err := loader.Load()
 	if err != nil {
-		if err != nil {
-			return nil, status.Errorf(
-				codes.InvalidArgument,
-				fmt.Sprintf("Cannot parse blogID :%v", err),
-			)
-		}
+		log.Fatal(err)
 	}
-
-	// create an empty BlogItem
-	data := &models.BlogItem{}
-	filter := bson.M{"_id": oid}
-
-	found := collection.FindOne(context.Background(), filter)
-
-	if err = found.Decode(data); err != nil {
-		return nil, status.Errorf(
-			codes.NotFound,
-			fmt.Sprintf("Cannot find blog with specified ID: %v", oid),
-			fmt.Sprintf("Error: %v", err),
-		)
+	if err := cfg.ServeMetrics(); err != nil {
+		log.Fatal(err)
 	}
-
-	// update internal struct
-	data.AuthorID = blog.GetAuthorId()
-	data.Content = blog.GetContent()
-	data.Title = blog.GetTitle()
-
-	if _, err = collection.ReplaceOne(context.Background(), filter, data); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Cannot update object in MongoDB with specified ID: %v", oid),
-			fmt.Sprintf("Error: %v", err),
-		)
+	if err := cfg.ExportTraces("blog"); err != nil {
+		log.Fatal(err)
 	}
 
-	return &blogpb.UpdateBlogResponse{
-		Blog: dataToBlogPb(data),
-	}, nil
-}
-
-func (*server) DeleteBlog(ctx context.Context, req *blogpb.DeleteBlogRequest) (*blogpb.DeleteBlogResponse, error) {
-	fmt.Println("Updating the blog...")
-
-	oid, err := primitive.ObjectIDFromHex(req.GetBlogId())
+	tokens, err := blogservice.Tokens(cfg.Blog)
 	if err != nil {
-		if err != nil {
-			return nil, status.Errorf(
-				codes.InvalidArgument,
-				fmt.Sprintf("Cannot parse blogID :%v", err),
-			)
-		}
+		log.Fatalf("failed to load tokens: %v", err)
 	}
-	filter := bson.M{"_id": oid}
 
-	deletedBlogRes, err := collection.DeleteOne(context.Background(), filter)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Connect)
+	stores, err := blogservice.OpenStores(ctx, cfg.Blog, cfg.Database)
+	cancel()
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Cannot delete object in MongoDB: %v with specified ID: %v", err, oid),
-		)
-	}
-
-	if deletedBlogRes.DeletedCount == 0 {
-		return nil, status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Cannot delete object in MongoDB: %v with specified ID: %v", err, oid),
-		)
+		log.Fatal(err)
 	}
 
-	return &blogpb.DeleteBlogResponse{
-		BlogId: oid.Hex(),
-	}, nil
-}
-
-func (*server) ListBlog(req *blogpb.ListBlogRequest, stream blogpb.BlogService_ListBlogServer) error {
-	fmt.Println("Listing blogs...")
-
-	cursor, err := collection.Find(context.Background(), primitive.D{})
+	lis, err := net.Listen("tcp", cfg.Listen)
 	if err != nil {
-		return status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Unknown internal error: %v", err),
-		)
-	}
-	defer cursor.Close(context.Background())
-
-	for cursor.Next(context.Background()) {
-		data := &models.BlogItem{}
-		if err := cursor.Decode(data); err != nil {
-			return status.Errorf(
-				codes.Internal,
-				fmt.Sprintf("Error while decoding data from MongdoDB: %v", err),
-			)
-		}
-		stream.Send(&blogpb.ListBlogResponse{Blog: dataToBlogPb(data)})
-	}
-
-	if err := cursor.Err(); err != nil {
-		return status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Unknown internal error: %v", err),
-		)
+		log.Fatalf("failed to listen: %v", err)
 	}
 
-	return nil
-}
-
-var collection *mongo.Collection
-
-func main() {
-	// if we crash the go code, we  get the file name and the line number
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-
-	fmt.Println("Connecting to MongoDB...")
-
-	// connect to mongodb
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+	opts, err := cfg.ServerOptions()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			log.Fatal(err)
-		}
-	}()
-
-	// If the Database is not exist then it will be created at first, the same is true for the Collection.
-	collection = client.Database("mydb").Collection("blog")
-
-	lis, err := net.Listen("tcp", "0.0.0.0:50051")
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
-	}
-
-	opts := []grpc.ServerOption{}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(
+			auth.UnaryServerInterceptor(tokens),
+			validate.UnaryServerInterceptor(blogservice.RequestRules),
+		),
+		grpc.ChainStreamInterceptor(
+			auth.StreamServerInterceptor(tokens),
+			validate.StreamServerInterceptor(blogservice.RequestRules),
+		),
+	)
 	s := grpc.NewServer(opts...)
-	blogpb.RegisterBlogServiceServer(s, &server{})
+	blogServer := blogservice.NewServer(stores.Blogs, stores.History, stores.Comments)
+	blogServer.Register(s)
+
+	// the server serves nothing but the blog services, so it is as healthy as
+	// their store
+	healthServer := healthcheck.Register(s)
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	go healthcheck.Watch(backgroundCtx, healthServer, "blog store", stores.Ping, cfg.Blog.HealthInterval, append([]string{""}, blogservice.Services...)...)
+	go blogServer.RunPurger(backgroundCtx, cfg.Blog.PurgeInterval, cfg.Blog.TrashRetention)
 
 	// Register reflection service on gRPC server.
 	reflection.Register(s)
@@ -272,11 +99,15 @@ func main() {
 
 	// Block until a signal is received
 	<-ch
+	// health checks fail from now on, so load balancers stop sending calls
+	healthServer.Shutdown()
+	fmt.Println("Stopping the trash purger...")
+	stopBackground()
 	fmt.Println("Stopping the server...")
-	s.GracefulStop()
+	cfg.GracefulStop(s)
 	fmt.Println("Closing the listener...")
 	lis.Close()
-	fmt.Println("Closing MongoDB Connection...")
-	client.Disconnect(context.Background())
+	stores.Close(context.Background())
+	tracing.DefaultTracer.Close()
 	fmt.Println("End of Program")
 }
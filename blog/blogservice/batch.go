@@ -0,0 +1,173 @@
+package blogservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchSize is the most items a single batch request may carry.
+const maxBatchSize = 1000
+
+func checkBatchSize(n int) error {
+	if n > maxBatchSize {
+		return rpcerr.New(
+			codes.InvalidArgument,
+			fmt.Sprintf("Batch has %d items, the maximum is %d", n, maxBatchSize),
+			rpcerr.Info(reasonBatchTooLarge, errorDomain, map[string]string{"max_batch_size": fmt.Sprint(maxBatchSize)}),
+		)
+	}
+	return nil
+}
+
+func okResult(data *models.BlogItem) *blogpb.BatchBlogResult {
+	return &blogpb.BatchBlogResult{
+		Status: status.New(codes.OK, "").Proto(),
+		Blog:   dataToBlogPb(data),
+	}
+}
+
+func errResult(err error) *blogpb.BatchBlogResult {
+	return &blogpb.BatchBlogResult{
+		Status: status.Convert(err).Proto(),
+	}
+}
+
+// parseBlogIDs parses the IDs of a batch request. The IDs that cannot be
+// parsed get their error result filled in right away.
+func parseBlogIDs(blogIDs []string) ([]primitive.ObjectID, []*blogpb.BatchBlogResult) {
+	oids := make([]primitive.ObjectID, len(blogIDs))
+	results := make([]*blogpb.BatchBlogResult, len(blogIDs))
+	for i, blogID := range blogIDs {
+		oid, err := parseBlogID(blogID)
+		if err != nil {
+			results[i] = errResult(err)
+			continue
+		}
+		oids[i] = oid
+	}
+
+	return oids, results
+}
+
+func (s *Server) BatchCreateBlogs(ctx context.Context, req *blogpb.BatchCreateBlogsRequest) (*blogpb.BatchCreateBlogsResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBatchSize(len(req.GetBlogs())); err != nil {
+		return nil, err
+	}
+
+	// invalid blogs fail on their own instead of failing the whole batch
+	now := now()
+	results := make([]*blogpb.BatchBlogResult, len(req.GetBlogs()))
+	var items []*models.BlogItem
+	var positions []int
+	for i, blog := range req.GetBlogs() {
+		if err := newBlogFields.Validate(blog); err != nil {
+			results[i] = errResult(err)
+			continue
+		}
+		items = append(items, &models.BlogItem{
+			AuthorID:  authorFor(id, blog.GetAuthorId()),
+			Title:     blog.GetTitle(),
+			Content:   blog.GetContent(),
+			Tags:      normalizeTags(blog.GetTags()),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Revision:  1,
+		})
+		positions = append(positions, i)
+	}
+
+	for i, err := range s.store.CreateMany(ctx, items) {
+		if err != nil {
+			results[positions[i]] = errResult(internalError(err))
+			continue
+		}
+		results[positions[i]] = okResult(items[i])
+		s.feed.publish(blogpb.WatchBlogsResponse_CREATED, dataToBlogPb(items[i]))
+	}
+
+	return &blogpb.BatchCreateBlogsResponse{Results: results}, nil
+}
+
+func (s *Server) BatchGetBlogs(ctx context.Context, req *blogpb.BatchGetBlogsRequest) (*blogpb.BatchGetBlogsResponse, error) {
+	if err := checkBatchSize(len(req.GetBlogIds())); err != nil {
+		return nil, err
+	}
+
+	oids, results := parseBlogIDs(req.GetBlogIds())
+	found, err := s.store.GetMany(ctx, oids)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	for i, oid := range oids {
+		if results[i] != nil {
+			continue
+		}
+		data, ok := found[oid]
+		if !ok || (!data.DeletedAt.IsZero() && !(req.GetShowDeleted() && seesTrashOf(ctx, data.AuthorID))) {
+			results[i] = errResult(storeError(store.ErrNotFound, oid))
+			continue
+		}
+		results[i] = okResult(data)
+	}
+
+	return &blogpb.BatchGetBlogsResponse{Results: results}, nil
+}
+
+func (s *Server) BatchDeleteBlogs(ctx context.Context, req *blogpb.BatchDeleteBlogsRequest) (*blogpb.BatchDeleteBlogsResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBatchSize(len(req.GetBlogIds())); err != nil {
+		return nil, err
+	}
+
+	oids, results := parseBlogIDs(req.GetBlogIds())
+	found, err := s.store.GetMany(ctx, oids)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	// only blogs outside the trash that the caller may change can be deleted,
+	// and each only if it is still the version checked, as in DeleteBlog
+	now := now()
+	set := bson.M{"deleted_at": now, "updated_at": now}
+	for i, oid := range oids {
+		if results[i] != nil {
+			continue
+		}
+		data, ok := found[oid]
+		if !ok || !data.DeletedAt.IsZero() {
+			results[i] = errResult(storeError(store.ErrNotFound, oid))
+			continue
+		}
+		if err := checkOwner(id, data); err != nil {
+			results[i] = errResult(err)
+			continue
+		}
+		data, err := s.store.Update(ctx, oid, set, data.Revision)
+		if err != nil {
+			results[i] = errResult(storeError(err, oid))
+			continue
+		}
+		results[i] = okResult(data)
+		s.feed.publish(blogpb.WatchBlogsResponse_DELETED, dataToBlogPb(data))
+	}
+
+	return &blogpb.BatchDeleteBlogsResponse{Results: results}, nil
+}
@@ -0,0 +1,151 @@
+package blogservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func resultCodes(results []*blogpb.BatchBlogResult) []codes.Code {
+	var got []codes.Code
+	for _, r := range results {
+		got = append(got, codes.Code(r.GetStatus().GetCode()))
+	}
+	return got
+}
+
+func equalCodes(a, b []codes.Code) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestServer_BatchBlogs(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	createRes, err := s.BatchCreateBlogs(ctx, &blogpb.BatchCreateBlogsRequest{Blogs: []*blogpb.Blog{
+		{Title: "first", Content: "1"}, {Title: "second", Content: "2"}, {Content: "untitled"}, {Title: "third", Content: "3"},
+	}})
+	if err != nil {
+		t.Fatalf("BatchCreateBlogs() had unexpected error: %v", err)
+	}
+	wantCreate := []codes.Code{codes.OK, codes.OK, codes.InvalidArgument, codes.OK}
+	if got := resultCodes(createRes.GetResults()); !equalCodes(got, wantCreate) {
+		t.Fatalf("BatchCreateBlogs() got codes %v, want %v", got, wantCreate)
+	}
+	var ids []string
+	for i, r := range createRes.GetResults() {
+		if i == 2 {
+			continue
+		}
+		if r.GetBlog().GetId() == "" {
+			t.Fatalf("BatchCreateBlogs() result %d got %v, want OK with an ID", i, r)
+		}
+		ids = append(ids, r.GetBlog().GetId())
+	}
+
+	deleteRes, err := s.BatchDeleteBlogs(ctx, &blogpb.BatchDeleteBlogsRequest{BlogIds: []string{ids[0], "bad", "5bdc29e661b75adcac496cf4"}})
+	if err != nil {
+		t.Fatalf("BatchDeleteBlogs() had unexpected error: %v", err)
+	}
+	want := []codes.Code{codes.OK, codes.InvalidArgument, codes.NotFound}
+	if got := resultCodes(deleteRes.GetResults()); !equalCodes(got, want) {
+		t.Errorf("BatchDeleteBlogs() got codes %v, want %v", got, want)
+	}
+	if deleteRes.GetResults()[0].GetBlog().GetDeletedAt() == nil {
+		t.Errorf("BatchDeleteBlogs() result 0 got no deleted_at")
+	}
+
+	getReq := &blogpb.BatchGetBlogsRequest{BlogIds: []string{ids[2], ids[0], ids[1]}}
+	getRes, err := s.BatchGetBlogs(ctx, getReq)
+	if err != nil {
+		t.Fatalf("BatchGetBlogs() had unexpected error: %v", err)
+	}
+	want = []codes.Code{codes.OK, codes.NotFound, codes.OK}
+	if got := resultCodes(getRes.GetResults()); !equalCodes(got, want) {
+		t.Errorf("BatchGetBlogs() got codes %v, want %v", got, want)
+	}
+	if got := getRes.GetResults()[0].GetBlog().GetTitle(); got != "third" {
+		t.Errorf("BatchGetBlogs() result 0 got title %q, want %q", got, "third")
+	}
+
+	getReq.ShowDeleted = true
+	getRes, err = s.BatchGetBlogs(ctx, getReq)
+	if err != nil {
+		t.Fatalf("BatchGetBlogs(show_deleted) had unexpected error: %v", err)
+	}
+	want = []codes.Code{codes.OK, codes.OK, codes.OK}
+	if got := resultCodes(getRes.GetResults()); !equalCodes(got, want) {
+		t.Errorf("BatchGetBlogs(show_deleted) got codes %v, want %v", got, want)
+	}
+}
+
+func TestServer_BatchDeleteRacingEdit(t *testing.T) {
+	ctx := adminContext()
+	blogs := &racingStore{BlogStore: store.NewMemoryBlogStore()}
+	s := NewServer(blogs, store.NewMemoryHistoryStore(), store.NewMemoryCommentStore())
+
+	var ids []string
+	for _, title := range []string{"edited", "deleted"} {
+		res, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: title, Content: title}})
+		if err != nil {
+			t.Fatalf("CreateBlog(%q) had unexpected error: %v", title, err)
+		}
+		ids = append(ids, res.GetBlog().GetId())
+	}
+	w, _, err := s.feed.subscribe("")
+	if err != nil {
+		t.Fatalf("subscribe() had unexpected error: %v", err)
+	}
+	defer s.feed.unsubscribe(w)
+
+	// the first blog is edited after the batch read it
+	edited, _ := primitive.ObjectIDFromHex(ids[0])
+	blogs.afterGet = func() {
+		if _, err := blogs.BlogStore.Update(context.Background(), edited, bson.M{"title": "again"}, store.AnyRevision); err != nil {
+			t.Fatalf("Update() had unexpected error: %v", err)
+		}
+	}
+	res, err := s.BatchDeleteBlogs(ctx, &blogpb.BatchDeleteBlogsRequest{BlogIds: ids})
+	if err != nil {
+		t.Fatalf("BatchDeleteBlogs() had unexpected error: %v", err)
+	}
+	want := []codes.Code{codes.Aborted, codes.OK}
+	if got := resultCodes(res.GetResults()); !equalCodes(got, want) {
+		t.Errorf("BatchDeleteBlogs() racing an edit got codes %v, want %v", got, want)
+	}
+	if data, err := blogs.Get(context.Background(), edited); err != nil || !data.DeletedAt.IsZero() {
+		t.Errorf("BatchDeleteBlogs() racing an edit left %+v, %v, want the edited blog outside the trash", data, err)
+	}
+	if event := <-w.events; event.GetType() != blogpb.WatchBlogsResponse_DELETED || event.GetBlog().GetId() != ids[1] {
+		t.Errorf("BatchDeleteBlogs() published %v, want DELETED for %v", event, ids[1])
+	}
+	select {
+	case event := <-w.events:
+		t.Errorf("BatchDeleteBlogs() also published %v, want a single event", event)
+	default:
+	}
+}
+
+func TestServer_BatchTooLarge(t *testing.T) {
+	s := newTestServer()
+
+	_, err := s.BatchGetBlogs(context.Background(), &blogpb.BatchGetBlogsRequest{BlogIds: make([]string, maxBatchSize+1)})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("BatchGetBlogs(%d ids) got code %v, want %v", maxBatchSize+1, code, codes.InvalidArgument)
+	}
+}
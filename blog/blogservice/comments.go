@@ -0,0 +1,277 @@
+package blogservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// commentServer implements the CommentService. Comments can only be reached
+// through a blog outside the trash; they are deleted when their blog is purged.
+type commentServer struct {
+	store store.CommentStore
+	blogs *Server
+}
+
+func newCommentServer(comments store.CommentStore, blogs *Server) *commentServer {
+	return &commentServer{
+		store: comments,
+		blogs: blogs,
+	}
+}
+
+func commentToPb(data *models.Comment) *blogpb.Comment {
+	comment := &blogpb.Comment{
+		Id:        data.ID.Hex(),
+		BlogId:    data.BlogID.Hex(),
+		AuthorId:  data.AuthorID,
+		Content:   data.Content,
+		CreatedAt: timeToPb(data.CreatedAt),
+		UpdatedAt: timeToPb(data.UpdatedAt),
+	}
+	if !data.ParentID.IsZero() {
+		comment.ParentId = data.ParentID.Hex()
+	}
+	return comment
+}
+
+// parseCommentID parses a comment ID sent by a client, returning an
+// InvalidArgument status error if it is not a valid ObjectID.
+func parseCommentID(commentID string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return oid, invalidIDError("comment_id", err)
+	}
+
+	return oid, nil
+}
+
+// commentNotFound is the NotFound status error for a missing comment.
+func commentNotFound(oid primitive.ObjectID) error {
+	return notFoundError(
+		commentResource,
+		oid.Hex(),
+		fmt.Sprintf("Cannot find comment with specified ID: %v", oid.Hex()),
+	)
+}
+
+// activeComment returns the comment with the given ID, or a NotFound status
+// error if it does not exist or its blog is in the trash.
+func (s *commentServer) activeComment(ctx context.Context, oid primitive.ObjectID) (*models.Comment, error) {
+	data, err := s.store.Get(ctx, oid)
+	if err == store.ErrNotFound {
+		return nil, commentNotFound(oid)
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if _, err := s.blogs.activeBlog(ctx, data.BlogID); err != nil {
+		return nil, commentNotFound(oid)
+	}
+
+	return data, nil
+}
+
+func (s *commentServer) AddComment(ctx context.Context, req *blogpb.AddCommentRequest) (*blogpb.AddCommentResponse, error) {
+	comment := req.GetComment()
+
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	blogID, err := parseBlogID(comment.GetBlogId())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.blogs.activeBlog(ctx, blogID); err != nil {
+		return nil, err
+	}
+
+	var parentID primitive.ObjectID
+	if comment.GetParentId() != "" {
+		if parentID, err = parseCommentID(comment.GetParentId()); err != nil {
+			return nil, err
+		}
+		parent, err := s.activeComment(ctx, parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.BlogID != blogID {
+			return nil, fieldError(
+				"comment.parent_id",
+				fmt.Errorf("Comment with specified ID %v is on another blog", parentID.Hex()),
+			)
+		}
+	}
+
+	now := now()
+	data := &models.Comment{
+		BlogID:    blogID,
+		ParentID:  parentID,
+		AuthorID:  id.Subject,
+		Content:   comment.GetContent(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.Create(ctx, data); err != nil {
+		return nil, internalError(err)
+	}
+
+	return &blogpb.AddCommentResponse{
+		Comment: commentToPb(data),
+	}, nil
+}
+
+func (s *commentServer) ListComments(req *blogpb.ListCommentsRequest, stream blogpb.CommentService_ListCommentsServer) error {
+	ctx := stream.Context()
+
+	blogID, err := parseBlogID(req.GetBlogId())
+	if err != nil {
+		return err
+	}
+	if _, err := s.blogs.activeBlog(ctx, blogID); err != nil {
+		return err
+	}
+
+	opts := store.CommentListOptions{
+		BlogID: blogID,
+		Limit:  int(req.GetPageSize()),
+	}
+	if req.GetParentId() != "" {
+		if opts.ParentID, err = parseCommentID(req.GetParentId()); err != nil {
+			return err
+		}
+	}
+	if req.GetPageToken() != "" {
+		if opts.After, err = decodeCommentPageToken(req); err != nil {
+			return fieldError("page_token", err)
+		}
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	if opts.Limit > maxPageSize {
+		opts.Limit = maxPageSize
+	}
+
+	// fetch one extra comment to find out whether there is a next page
+	pageSize := opts.Limit
+	opts.Limit++
+
+	var page []*models.Comment
+	err = s.store.List(ctx, opts, func(data *models.Comment) error {
+		page = append(page, data)
+		return nil
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	more := len(page) > pageSize
+	if more {
+		page = page[:pageSize]
+	}
+	for i, data := range page {
+		res := &blogpb.ListCommentsResponse{Comment: commentToPb(data)}
+		if more && i == len(page)-1 {
+			res.NextPageToken = encodeCommentPageToken(data.ID, req)
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *commentServer) EditComment(ctx context.Context, req *blogpb.EditCommentRequest) (*blogpb.EditCommentResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oid, err := parseCommentID(req.GetCommentId())
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.activeComment(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCommentOwner(id, data); err != nil {
+		return nil, err
+	}
+
+	data, err = s.store.Update(ctx, oid, bson.M{"content": req.GetContent(), "updated_at": now()})
+	if err == store.ErrNotFound {
+		return nil, commentNotFound(oid)
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return &blogpb.EditCommentResponse{
+		Comment: commentToPb(data),
+	}, nil
+}
+
+func (s *commentServer) DeleteComment(ctx context.Context, req *blogpb.DeleteCommentRequest) (*blogpb.DeleteCommentResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oid, err := parseCommentID(req.GetCommentId())
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.activeComment(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCommentOwner(id, data); err != nil {
+		return nil, err
+	}
+
+	// replies go with the comment they answer, however deep the thread
+	thread, err := s.thread(ctx, data)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if err := s.store.DeleteMany(ctx, thread); err != nil {
+		return nil, internalError(err)
+	}
+
+	return &blogpb.DeleteCommentResponse{
+		CommentId:    oid.Hex(),
+		DeletedCount: int32(len(thread)),
+	}, nil
+}
+
+// thread returns the IDs of root and of every reply under it.
+func (s *commentServer) thread(ctx context.Context, root *models.Comment) ([]primitive.ObjectID, error) {
+	replies := map[primitive.ObjectID][]primitive.ObjectID{}
+	err := s.store.List(ctx, store.CommentListOptions{BlogID: root.BlogID}, func(data *models.Comment) error {
+		if !data.ParentID.IsZero() {
+			replies[data.ParentID] = append(replies[data.ParentID], data.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	thread := []primitive.ObjectID{root.ID}
+	for i := 0; i < len(thread); i++ {
+		thread = append(thread, replies[thread[i]]...)
+	}
+
+	return thread, nil
+}
@@ -0,0 +1,138 @@
+package blogservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// commentStream collects the comments sent on a ListComments stream.
+type commentStream struct {
+	grpc.ServerStream
+
+	ctx       context.Context
+	responses []*blogpb.ListCommentsResponse
+}
+
+func (s *commentStream) Context() context.Context { return s.ctx }
+
+func (s *commentStream) Send(res *blogpb.ListCommentsResponse) error {
+	s.responses = append(s.responses, res)
+	return nil
+}
+
+func listComments(t *testing.T, s *commentServer, req *blogpb.ListCommentsRequest) []*blogpb.ListCommentsResponse {
+	t.Helper()
+
+	stream := &commentStream{ctx: context.Background()}
+	if err := s.ListComments(req, stream); err != nil {
+		t.Fatalf("ListComments(%v) had unexpected error: %v", req, err)
+	}
+	return stream.responses
+}
+
+func TestCommentServer(t *testing.T) {
+	ctx := adminContext()
+	blogs := newTestServer()
+	s := newCommentServer(blogs.comments, blogs)
+	john := auth.NewContext(context.Background(), &auth.Identity{Subject: "John"})
+
+	createRes, err := blogs.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "commented"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blogID := createRes.GetBlog().GetId()
+
+	add := func(ctx context.Context, parentID, content string) *blogpb.Comment {
+		t.Helper()
+		res, err := s.AddComment(ctx, &blogpb.AddCommentRequest{Comment: &blogpb.Comment{BlogId: blogID, ParentId: parentID, Content: content}})
+		if err != nil {
+			t.Fatalf("AddComment(%q) had unexpected error: %v", content, err)
+		}
+		return res.GetComment()
+	}
+	first := add(john, "", "first")
+	reply := add(ctx, first.GetId(), "reply")
+	add(john, reply.GetId(), "reply to reply")
+	add(ctx, "", "second")
+
+	if got := first.GetAuthorId(); got != "John" {
+		t.Errorf("AddComment() got author %q, want John", got)
+	}
+
+	page := listComments(t, s, &blogpb.ListCommentsRequest{BlogId: blogID, PageSize: 3})
+	if len(page) != 3 || page[2].GetNextPageToken() == "" {
+		t.Fatalf("ListComments(page_size 3) got %v, want 3 comments and a next_page_token", page)
+	}
+	next := listComments(t, s, &blogpb.ListCommentsRequest{BlogId: blogID, PageSize: 3, PageToken: page[2].GetNextPageToken()})
+	if len(next) != 1 || next[0].GetComment().GetContent() != "second" || next[0].GetNextPageToken() != "" {
+		t.Errorf("ListComments(second page) got %v, want only the second comment", next)
+	}
+	replies := listComments(t, s, &blogpb.ListCommentsRequest{BlogId: blogID, ParentId: first.GetId()})
+	if len(replies) != 1 || replies[0].GetComment().GetId() != reply.GetId() {
+		t.Errorf("ListComments(parent) got %v, want the reply", replies)
+	}
+
+	_, err = s.EditComment(john, &blogpb.EditCommentRequest{CommentId: reply.GetId(), Content: "hijacked"})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("EditComment(non-owner) got code %v, want %v", code, codes.PermissionDenied)
+	}
+	editRes, err := s.EditComment(john, &blogpb.EditCommentRequest{CommentId: first.GetId(), Content: "edited"})
+	if err != nil {
+		t.Fatalf("EditComment() had unexpected error: %v", err)
+	}
+	if got := editRes.GetComment().GetContent(); got != "edited" {
+		t.Errorf("EditComment() got content %q, want %q", got, "edited")
+	}
+
+	deleteRes, err := s.DeleteComment(john, &blogpb.DeleteCommentRequest{CommentId: first.GetId()})
+	if err != nil {
+		t.Fatalf("DeleteComment() had unexpected error: %v", err)
+	}
+	if got := deleteRes.GetDeletedCount(); got != 3 {
+		t.Errorf("DeleteComment() got deleted_count %d, want 3", got)
+	}
+	if got := listComments(t, s, &blogpb.ListCommentsRequest{BlogId: blogID}); len(got) != 1 {
+		t.Errorf("ListComments() after DeleteComment got %d comments, want 1", len(got))
+	}
+}
+
+func TestCommentServer_BlogDeletion(t *testing.T) {
+	ctx := adminContext()
+	blogs := newTestServer()
+	s := newCommentServer(blogs.comments, blogs)
+
+	createRes, err := blogs.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "doomed"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blogID := createRes.GetBlog().GetId()
+	addRes, err := s.AddComment(ctx, &blogpb.AddCommentRequest{Comment: &blogpb.Comment{BlogId: blogID, Content: "hello"}})
+	if err != nil {
+		t.Fatalf("AddComment() had unexpected error: %v", err)
+	}
+	commentID := addRes.GetComment().GetId()
+
+	if _, err := blogs.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: blogID}); err != nil {
+		t.Fatalf("DeleteBlog() had unexpected error: %v", err)
+	}
+	err = s.ListComments(&blogpb.ListCommentsRequest{BlogId: blogID}, &commentStream{ctx: ctx})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("ListComments(trashed blog) got code %v, want %v", code, codes.NotFound)
+	}
+
+	if _, err := blogs.PurgeBlog(ctx, &blogpb.PurgeBlogRequest{BlogId: blogID}); err != nil {
+		t.Fatalf("PurgeBlog() had unexpected error: %v", err)
+	}
+	oid, _ := parseCommentID(commentID)
+	if _, err := blogs.comments.Get(ctx, oid); err != store.ErrNotFound {
+		t.Errorf("comments.Get() after PurgeBlog got error %v, want %v", err, store.ErrNotFound)
+	}
+}
@@ -0,0 +1,74 @@
+package blogservice
+
+import (
+	"strings"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+)
+
+// maxDiffCells bounds the table lineDiff builds. Larger inputs are reported
+// as all lines deleted and inserted.
+const maxDiffCells = 1 << 22
+
+// lineDiff returns the line-based diff that turns from into to, keeping their
+// longest common subsequence of lines as EQUAL lines.
+func lineDiff(from, to string) []*blogpb.DiffLine {
+	a, b := splitLines(from), splitLines(to)
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return append(diffLines(blogpb.DiffLine_DELETE, a), diffLines(blogpb.DiffLine_INSERT, b)...)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []*blogpb.DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, &blogpb.DiffLine{Op: blogpb.DiffLine_EQUAL, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, &blogpb.DiffLine{Op: blogpb.DiffLine_DELETE, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, &blogpb.DiffLine{Op: blogpb.DiffLine_INSERT, Text: b[j]})
+			j++
+		}
+	}
+	diff = append(diff, diffLines(blogpb.DiffLine_DELETE, a[i:])...)
+	diff = append(diff, diffLines(blogpb.DiffLine_INSERT, b[j:])...)
+
+	return diff
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func diffLines(op blogpb.DiffLine_Op, lines []string) []*blogpb.DiffLine {
+	diff := make([]*blogpb.DiffLine, len(lines))
+	for i, line := range lines {
+		diff[i] = &blogpb.DiffLine{Op: op, Text: line}
+	}
+	return diff
+}
@@ -0,0 +1,87 @@
+package blogservice
+
+import (
+	"fmt"
+
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+)
+
+// errorDomain is the domain of the ErrorInfo details the blog server sends.
+const errorDomain = "blog.grpc-go-course"
+
+// Reasons of the ErrorInfo details the blog server sends, which clients can
+// branch on instead of parsing messages.
+const (
+	reasonInternal           = "INTERNAL"
+	reasonInvalidArgument    = "INVALID_ARGUMENT"
+	reasonInvalidID          = "INVALID_ID"
+	reasonNotFound           = "NOT_FOUND"
+	reasonNotInTrash         = "NOT_IN_TRASH"
+	reasonRevisionMismatch   = "REVISION_MISMATCH"
+	reasonNotAuthor          = "NOT_AUTHOR"
+	reasonNotAdmin           = "NOT_ADMIN"
+	reasonUnauthenticated    = "UNAUTHENTICATED"
+	reasonBatchTooLarge      = "BATCH_TOO_LARGE"
+	reasonResumeTokenExpired = "RESUME_TOKEN_EXPIRED"
+	reasonWatcherTooSlow     = "WATCHER_TOO_SLOW"
+)
+
+// Resource types named by the ResourceInfo details of NotFound errors.
+const (
+	blogResource     = "blog"
+	revisionResource = "blog revision"
+	commentResource  = "comment"
+)
+
+// internalError is the Internal status error for an unexpected failure.
+func internalError(err error) error {
+	return rpcerr.New(
+		codes.Internal,
+		fmt.Sprintf("Unknown internal error: %v", err),
+		rpcerr.Info(reasonInternal, errorDomain, nil),
+	)
+}
+
+// notFoundError is the NotFound status error for a missing resource.
+func notFoundError(resourceType, name, msg string) error {
+	return rpcerr.New(
+		codes.NotFound,
+		msg,
+		rpcerr.Info(reasonNotFound, errorDomain, map[string]string{"resource_type": resourceType}),
+		rpcerr.Resource(resourceType, name, msg),
+	)
+}
+
+// fieldError is the InvalidArgument status error for a request field that
+// err explains the problem with.
+func fieldError(field string, err error) error {
+	return rpcerr.New(
+		codes.InvalidArgument,
+		err.Error(),
+		rpcerr.Info(reasonInvalidArgument, errorDomain, map[string]string{"field": field}),
+		rpcerr.BadRequest(rpcerr.Violation(field, err.Error())),
+	)
+}
+
+// invalidIDError is the InvalidArgument status error for an ID that is not an ObjectID.
+func invalidIDError(field string, err error) error {
+	msg := fmt.Sprintf("Cannot parse %s :%v", field, err)
+	return rpcerr.New(
+		codes.InvalidArgument,
+		msg,
+		rpcerr.Info(reasonInvalidID, errorDomain, map[string]string{"field": field}),
+		rpcerr.BadRequest(rpcerr.Violation(field, msg)),
+	)
+}
+
+// blogError is a status error with reason about the blog with the given ID.
+func blogError(code codes.Code, reason string, oid primitive.ObjectID, msg string) error {
+	return rpcerr.New(
+		code,
+		msg,
+		rpcerr.Info(reason, errorDomain, map[string]string{"blog_id": oid.Hex()}),
+	)
+}
@@ -0,0 +1,42 @@
+package blogservice
+
+import (
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_ErrorDetails(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	const missing = "5bdc29e661b75adcac496cf4"
+	_, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: missing})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Fatalf("ReadBlog(missing) got code %v, want %v", code, codes.NotFound)
+	}
+	if info := rpcerr.InfoOf(err); info.GetReason() != reasonNotFound || info.GetDomain() != errorDomain {
+		t.Errorf("ReadBlog(missing) got ErrorInfo %v, want reason %s in %s", info, reasonNotFound, errorDomain)
+	}
+	if res := rpcerr.ResourceOf(err); res.GetResourceType() != blogResource || res.GetResourceName() != missing {
+		t.Errorf("ReadBlog(missing) got ResourceInfo %v, want blog %s", res, missing)
+	}
+
+	_, err = s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: "bad"})
+	if got := rpcerr.ViolationsOf(err); len(got) != 1 || got[0].GetField() != "blog_id" {
+		t.Errorf("ReadBlog(bad) got violations %v, want one for blog_id", got)
+	}
+
+	createRes, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "t", Content: "c"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	_, err = s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: createRes.GetBlog().GetId(), Title: "t", Content: "c"}, ExpectedRevision: 7})
+	if got := rpcerr.ReasonOf(err); got != reasonRevisionMismatch {
+		t.Errorf("UpdateBlog(stale) got reason %q, want %q", got, reasonRevisionMismatch)
+	}
+}
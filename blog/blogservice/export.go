@@ -0,0 +1,184 @@
+package blogservice
+
+import (
+	"context"
+	"io"
+	"log"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s *Server) ExportBlogs(req *blogpb.ExportBlogsRequest, stream blogpb.BlogService_ExportBlogsServer) error {
+	id, err := caller(stream.Context())
+	if err != nil {
+		return err
+	}
+	if err := checkAdmin(id); err != nil {
+		return err
+	}
+
+	opts, err := listOptions(&blogpb.ListBlogRequest{AuthorId: req.GetAuthorId(), Trash: req.GetTrash()})
+	if err != nil {
+		return err
+	}
+
+	err = s.store.List(stream.Context(), opts, func(data *models.BlogItem) error {
+		return stream.Send(&blogpb.ExportBlogsResponse{Blog: dataToBlogPb(data)})
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	return nil
+}
+
+// ImportBlogs writes every blog received under its own ID, so importing an
+// export again, or resuming an import that failed half way, leaves the blogs
+// already imported as they are. A blog imported over a different stored one
+// becomes its next revision, see importBlog.
+func (s *Server) ImportBlogs(stream blogpb.BlogService_ImportBlogsServer) error {
+	id, err := caller(stream.Context())
+	if err != nil {
+		return err
+	}
+	if err := checkAdmin(id); err != nil {
+		return err
+	}
+
+	res := &blogpb.ImportBlogsResponse{}
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(res)
+		}
+		if err != nil {
+			return err
+		}
+
+		data, err := importedBlog(req.GetBlog())
+		if err != nil {
+			return err
+		}
+		stored, outcome, err := s.importBlog(stream.Context(), data)
+		if err != nil {
+			return storeError(err, data.ID)
+		}
+		switch outcome {
+		case importCreated:
+			res.CreatedCount++
+			s.feed.publish(blogpb.WatchBlogsResponse_CREATED, dataToBlogPb(stored))
+		case importReplaced:
+			res.ReplacedCount++
+			s.feed.publish(blogpb.WatchBlogsResponse_UPDATED, dataToBlogPb(stored))
+		case importUnchanged:
+			res.UnchangedCount++
+		}
+	}
+}
+
+// importOutcome tells what importing a blog did to the stored blogs.
+type importOutcome int
+
+const (
+	importCreated importOutcome = iota
+	importReplaced
+	importUnchanged
+)
+
+// importBlog writes an imported blog and returns the blog stored. A blog with
+// a new ID is stored as it is, and one identical to the stored blog is not
+// written at all. Over a different stored blog, the import is written like an
+// update: it gets the revision after the stored one, whatever revision it was
+// exported at, and the stored version is archived, so that an older export
+// cannot move the revision backwards or clash with the history.
+func (s *Server) importBlog(ctx context.Context, data *models.BlogItem) (*models.BlogItem, importOutcome, error) {
+	for attempt := 1; ; attempt++ {
+		prior, err := s.store.Get(ctx, data.ID)
+		if err == store.ErrNotFound {
+			created, err := s.store.Put(ctx, data)
+			if !created {
+				return data, importReplaced, err
+			}
+			return data, importCreated, err
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if sameBlog(prior, data) {
+			return prior, importUnchanged, nil
+		}
+
+		set := bson.M{
+			"author_id":  data.AuthorID,
+			"title":      data.Title,
+			"content":    data.Content,
+			"tags":       data.Tags,
+			"created_at": data.CreatedAt,
+			"updated_at": data.UpdatedAt,
+			"deleted_at": nil,
+		}
+		if !data.DeletedAt.IsZero() {
+			set["deleted_at"] = data.DeletedAt
+		}
+		updated, err := s.store.Update(ctx, data.ID, set, prior.Revision)
+		if err == store.ErrRevisionMismatch && attempt < maxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// the import went through, so a failure here only loses history
+		if err := s.history.Append(ctx, prior); err != nil {
+			log.Printf("failed to archive revision %d of blog %v: %v", prior.Revision, data.ID.Hex(), err)
+		}
+
+		return updated, importReplaced, nil
+	}
+}
+
+// sameBlog reports whether an imported blog is the version already stored.
+func sameBlog(stored, imported *models.BlogItem) bool {
+	return stored.Revision == imported.Revision &&
+		stored.AuthorID == imported.AuthorID &&
+		stored.Title == imported.Title &&
+		stored.Content == imported.Content &&
+		equalTags(stored.Tags, imported.Tags) &&
+		stored.DeletedAt.IsZero() == imported.DeletedAt.IsZero()
+}
+
+// importedBlog converts an imported blog to the item to store, keeping its ID,
+// author, revision and timestamps and filling in the ones it lacks.
+func importedBlog(blog *blogpb.Blog) (*models.BlogItem, error) {
+	oid, err := parseBlogID(blog.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	data := &models.BlogItem{
+		ID:        oid,
+		AuthorID:  blog.GetAuthorId(),
+		Title:     blog.GetTitle(),
+		Content:   blog.GetContent(),
+		Tags:      normalizeTags(blog.GetTags()),
+		CreatedAt: pbToTime(blog.GetCreatedAt()),
+		UpdatedAt: pbToTime(blog.GetUpdatedAt()),
+		Revision:  blog.GetRevision(),
+		DeletedAt: pbToTime(blog.GetDeletedAt()),
+	}
+	if data.CreatedAt.IsZero() {
+		data.CreatedAt = now()
+	}
+	if data.UpdatedAt.IsZero() {
+		data.UpdatedAt = data.CreatedAt
+	}
+	if data.Revision == 0 {
+		data.Revision = 1
+	}
+
+	return data, nil
+}
@@ -0,0 +1,166 @@
+package blogservice
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// exportStream collects the blogs sent on an ExportBlogs stream.
+type exportStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	blogs []*blogpb.Blog
+}
+
+func (s *exportStream) Context() context.Context { return s.ctx }
+
+func (s *exportStream) Send(res *blogpb.ExportBlogsResponse) error {
+	s.blogs = append(s.blogs, res.GetBlog())
+	return nil
+}
+
+// importStream feeds blogs to an ImportBlogs call and keeps its response.
+type importStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	blogs []*blogpb.Blog
+	res   *blogpb.ImportBlogsResponse
+}
+
+func (s *importStream) Context() context.Context { return s.ctx }
+
+func (s *importStream) Recv() (*blogpb.ImportBlogsRequest, error) {
+	if len(s.blogs) == 0 {
+		return nil, io.EOF
+	}
+	blog := s.blogs[0]
+	s.blogs = s.blogs[1:]
+	return &blogpb.ImportBlogsRequest{Blog: blog}, nil
+}
+
+func (s *importStream) SendAndClose(res *blogpb.ImportBlogsResponse) error {
+	s.res = res
+	return nil
+}
+
+func TestServer_ExportImport(t *testing.T) {
+	ctx := adminContext()
+	src := newTestServer()
+
+	for _, title := range []string{"first", "second"} {
+		if _, err := src.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{AuthorId: "khoi", Title: title, Content: "content"}}); err != nil {
+			t.Fatalf("CreateBlog(%q) had unexpected error: %v", title, err)
+		}
+	}
+	exported := &exportStream{ctx: ctx}
+	if err := src.ExportBlogs(&blogpb.ExportBlogsRequest{Trash: blogpb.ListBlogRequest_WITH_TRASHED}, exported); err != nil {
+		t.Fatalf("ExportBlogs() had unexpected error: %v", err)
+	}
+	if len(exported.blogs) != 2 {
+		t.Fatalf("ExportBlogs() got %d blogs, want 2", len(exported.blogs))
+	}
+
+	// importing twice creates the blogs, then leaves them as they are
+	dst := newTestServer()
+	for i, want := range []*blogpb.ImportBlogsResponse{{CreatedCount: 2}, {UnchangedCount: 2}} {
+		stream := &importStream{ctx: ctx, blogs: exported.blogs}
+		if err := dst.ImportBlogs(stream); err != nil {
+			t.Fatalf("ImportBlogs() #%d had unexpected error: %v", i, err)
+		}
+		if !proto.Equal(stream.res, want) {
+			t.Errorf("ImportBlogs() #%d got %v, want %v", i, stream.res, want)
+		}
+	}
+
+	for _, blog := range exported.blogs {
+		res, err := dst.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: blog.GetId()})
+		if err != nil {
+			t.Fatalf("ReadBlog(%v) had unexpected error: %v", blog.GetId(), err)
+		}
+		got := res.GetBlog()
+		got.Excerpt, got.WordCount, got.ReadingTimeMinutes = "", 0, 0
+		if !proto.Equal(got, blog) {
+			t.Errorf("ReadBlog() after import got %v, want %v", got, blog)
+		}
+		revisions, err := dst.ListBlogRevisions(ctx, &blogpb.ListBlogRevisionsRequest{BlogId: blog.GetId()})
+		if err != nil {
+			t.Fatalf("ListBlogRevisions(%v) had unexpected error: %v", blog.GetId(), err)
+		}
+		if len(revisions.GetRevisions()) != 0 {
+			t.Errorf("ListBlogRevisions() after importing twice got %v, want no history", revisions.GetRevisions())
+		}
+	}
+}
+
+func TestServer_ImportOlderExport(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	createRes, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "first", Content: "first"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blogID := createRes.GetBlog().GetId()
+	exported := &exportStream{ctx: ctx}
+	if err := s.ExportBlogs(&blogpb.ExportBlogsRequest{}, exported); err != nil {
+		t.Fatalf("ExportBlogs() had unexpected error: %v", err)
+	}
+	if _, err := s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: blogID, Title: "second", Content: "second"}}); err != nil {
+		t.Fatalf("UpdateBlog() had unexpected error: %v", err)
+	}
+
+	// the export of revision 1 goes over revision 2 as revision 3
+	stream := &importStream{ctx: ctx, blogs: exported.blogs}
+	if err := s.ImportBlogs(stream); err != nil {
+		t.Fatalf("ImportBlogs() had unexpected error: %v", err)
+	}
+	readRes, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: blogID})
+	if err != nil {
+		t.Fatalf("ReadBlog() had unexpected error: %v", err)
+	}
+	if got := readRes.GetBlog(); got.GetRevision() != 3 || got.GetTitle() != "first" {
+		t.Errorf("ReadBlog() after import got revision %d title %q, want 3 and first", got.GetRevision(), got.GetTitle())
+	}
+
+	// the versions replaced by the update and the import are both kept, and
+	// updating again archives revision 3
+	if _, err := s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: blogID, Title: "third", Content: "third"}}); err != nil {
+		t.Fatalf("UpdateBlog() after import had unexpected error: %v", err)
+	}
+	listRes, err := s.ListBlogRevisions(ctx, &blogpb.ListBlogRevisionsRequest{BlogId: blogID})
+	if err != nil {
+		t.Fatalf("ListBlogRevisions() had unexpected error: %v", err)
+	}
+	var got []int64
+	var titles []string
+	for _, rev := range listRes.GetRevisions() {
+		got = append(got, rev.GetRevision())
+		titles = append(titles, rev.GetTitle())
+	}
+	if len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 1 || titles[1] != "second" {
+		t.Errorf("ListBlogRevisions() got revisions %v titled %v, want 3, 2 second and 1", got, titles)
+	}
+}
+
+func TestServer_ExportImportNeedAdmin(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "khoi"})
+	s := newTestServer()
+
+	err := s.ExportBlogs(&blogpb.ExportBlogsRequest{}, &exportStream{ctx: ctx})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("ExportBlogs() as an author got %v, want PermissionDenied", err)
+	}
+	err = s.ImportBlogs(&importStream{ctx: ctx})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("ImportBlogs() as an author got %v, want PermissionDenied", err)
+	}
+}
@@ -0,0 +1,165 @@
+package blogservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxUpdateAttempts bounds how often an update that did not ask for a specific
+// revision starts over after racing with another writer.
+const maxUpdateAttempts = 3
+
+// updateWithHistory applies set to a blog outside the trash that id may change,
+// and archives the version it replaced. The update is conditional on the version read, so the
+// archived version is always the one that was replaced; when the caller did not
+// ask for a specific revision, losing a race just makes it start over.
+func (s *Server) updateWithHistory(ctx context.Context, id *auth.Identity, oid primitive.ObjectID, set bson.M, expectedRevision int64) (*models.BlogItem, error) {
+	for attempt := 1; ; attempt++ {
+		prior, err := s.activeBlog(ctx, oid)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkOwner(id, prior); err != nil {
+			return nil, err
+		}
+		if expectedRevision != store.AnyRevision && expectedRevision != prior.Revision {
+			return nil, storeError(store.ErrRevisionMismatch, oid)
+		}
+
+		data, err := s.store.Update(ctx, oid, set, prior.Revision)
+		if err == store.ErrRevisionMismatch && expectedRevision == store.AnyRevision && attempt < maxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, storeError(err, oid)
+		}
+
+		// the update went through, so a failure here only loses history
+		if err := s.history.Append(ctx, prior); err != nil {
+			log.Printf("failed to archive revision %d of blog %v: %v", prior.Revision, oid.Hex(), err)
+		}
+
+		return data, nil
+	}
+}
+
+// blogAtRevision returns the given version of a blog, which is either its
+// current version or an archived one.
+func (s *Server) blogAtRevision(ctx context.Context, current *models.BlogItem, revision int64) (*models.BlogItem, error) {
+	if revision == current.Revision {
+		return current, nil
+	}
+
+	rev, err := s.history.Get(ctx, current.ID, revision)
+	if err == store.ErrNotFound {
+		return nil, notFoundError(
+			revisionResource,
+			fmt.Sprintf("%v@%d", current.ID.Hex(), revision),
+			fmt.Sprintf("Cannot find revision %d of blog with specified ID: %v", revision, current.ID.Hex()),
+		)
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return &rev.Blog, nil
+}
+
+func (s *Server) ListBlogRevisions(ctx context.Context, req *blogpb.ListBlogRevisionsRequest) (*blogpb.ListBlogRevisionsResponse, error) {
+	oid, err := parseBlogID(req.GetBlogId())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.readableBlog(ctx, oid); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.history.List(ctx, oid)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	res := &blogpb.ListBlogRevisionsResponse{}
+	for _, rev := range revisions {
+		res.Revisions = append(res.Revisions, dataToBlogPb(&rev.Blog))
+	}
+
+	return res, nil
+}
+
+func (s *Server) GetBlogRevision(ctx context.Context, req *blogpb.GetBlogRevisionRequest) (*blogpb.GetBlogRevisionResponse, error) {
+	oid, err := parseBlogID(req.GetBlogId())
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.readableBlog(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.blogAtRevision(ctx, current, req.GetRevision())
+	if err != nil {
+		return nil, err
+	}
+	compareTo := current
+	if req.GetCompareToRevision() != 0 {
+		if compareTo, err = s.blogAtRevision(ctx, current, req.GetCompareToRevision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &blogpb.GetBlogRevisionResponse{
+		Blog: dataToBlogPb(data),
+		Diff: lineDiff(compareTo.Content, data.Content),
+	}, nil
+}
+
+func (s *Server) RollbackBlog(ctx context.Context, req *blogpb.RollbackBlogRequest) (*blogpb.RollbackBlogResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oid, err := parseBlogID(req.GetBlogId())
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.activeBlog(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.blogAtRevision(ctx, current, req.GetRevision())
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{
+		"title":      target.Title,
+		"content":    target.Content,
+		"tags":       target.Tags,
+		"updated_at": now(),
+	}
+	// only admins hand blogs over to other authors
+	if id.IsAdmin() {
+		set["author_id"] = target.AuthorID
+	}
+	data, err := s.updateWithHistory(ctx, id, oid, set, req.GetExpectedRevision())
+	if err != nil {
+		return nil, err
+	}
+	s.feed.publish(blogpb.WatchBlogsResponse_UPDATED, dataToBlogPb(data))
+
+	return &blogpb.RollbackBlogResponse{
+		Blog: dataToBlogPb(data),
+		Diff: lineDiff(current.Content, data.Content),
+	}, nil
+}
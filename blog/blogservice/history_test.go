@@ -0,0 +1,106 @@
+package blogservice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{"empty", "", "", nil},
+		{"insert all", "", "a\nb", []string{"+a", "+b"}},
+		{"delete all", "a\nb", "", []string{"-a", "-b"}},
+		{"unchanged", "a\nb", "a\nb", []string{" a", " b"}},
+		{"replace middle", "a\nb\nc", "a\nx\nc", []string{" a", "-b", "+x", " c"}},
+		{"append", "a", "a\nb", []string{" a", "+b"}},
+	}
+	prefix := map[blogpb.DiffLine_Op]string{
+		blogpb.DiffLine_EQUAL:  " ",
+		blogpb.DiffLine_INSERT: "+",
+		blogpb.DiffLine_DELETE: "-",
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, line := range lineDiff(tt.from, tt.to) {
+			got = append(got, prefix[line.GetOp()]+line.GetText())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lineDiff(%s) got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestServer_RevisionHistory(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	createRes, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "v1", Content: "one"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	id := createRes.GetBlog().GetId()
+	for _, content := range []string{"one\ntwo", "one\ntwo\nthree"} {
+		if _, err := s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: id, Title: "v", Content: content}}); err != nil {
+			t.Fatalf("UpdateBlog(%q) had unexpected error: %v", content, err)
+		}
+	}
+
+	listRes, err := s.ListBlogRevisions(ctx, &blogpb.ListBlogRevisionsRequest{BlogId: id})
+	if err != nil {
+		t.Fatalf("ListBlogRevisions() had unexpected error: %v", err)
+	}
+	var revisions []int64
+	for _, blog := range listRes.GetRevisions() {
+		revisions = append(revisions, blog.GetRevision())
+	}
+	if want := []int64{2, 1}; !reflect.DeepEqual(revisions, want) {
+		t.Errorf("ListBlogRevisions() got revisions %v, want %v", revisions, want)
+	}
+
+	getRes, err := s.GetBlogRevision(ctx, &blogpb.GetBlogRevisionRequest{BlogId: id, Revision: 1})
+	if err != nil {
+		t.Fatalf("GetBlogRevision(1) had unexpected error: %v", err)
+	}
+	if got := getRes.GetBlog().GetTitle(); got != "v1" {
+		t.Errorf("GetBlogRevision(1) got title %q, want %q", got, "v1")
+	}
+	if got := len(getRes.GetDiff()); got != 3 {
+		t.Errorf("GetBlogRevision(1) got %d diff lines, want 3", got)
+	}
+
+	_, err = s.GetBlogRevision(ctx, &blogpb.GetBlogRevisionRequest{BlogId: id, Revision: 9})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("GetBlogRevision(9) got code %v, want %v", code, codes.NotFound)
+	}
+
+	_, err = s.RollbackBlog(ctx, &blogpb.RollbackBlogRequest{BlogId: id, Revision: 1, ExpectedRevision: 2})
+	if code := status.Code(err); code != codes.Aborted {
+		t.Errorf("RollbackBlog(stale) got code %v, want %v", code, codes.Aborted)
+	}
+	rollbackRes, err := s.RollbackBlog(ctx, &blogpb.RollbackBlogRequest{BlogId: id, Revision: 1, ExpectedRevision: 3})
+	if err != nil {
+		t.Fatalf("RollbackBlog() had unexpected error: %v", err)
+	}
+	blog := rollbackRes.GetBlog()
+	if blog.GetTitle() != "v1" || blog.GetContent() != "one" || blog.GetRevision() != 4 {
+		t.Errorf("RollbackBlog() got %v, want title v1, content one and revision 4", blog)
+	}
+
+	listRes, err = s.ListBlogRevisions(ctx, &blogpb.ListBlogRevisionsRequest{BlogId: id})
+	if err != nil {
+		t.Fatalf("ListBlogRevisions() had unexpected error: %v", err)
+	}
+	if got := len(listRes.GetRevisions()); got != 3 {
+		t.Errorf("ListBlogRevisions() after rollback got %d revisions, want 3", got)
+	}
+}
@@ -0,0 +1,120 @@
+package blogservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+	"github.com/mirageruler/grpc-go-course/config"
+	"github.com/mirageruler/grpc-go-course/metrics"
+	"github.com/mirageruler/grpc-go-course/tracing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// DefaultBlog returns the settings the blog services start from.
+func DefaultBlog() config.Blog {
+	return config.Blog{
+		Store:          "mongo",
+		TrashRetention: 30 * 24 * time.Hour,
+		PurgeInterval:  time.Hour,
+		HealthInterval: 10 * time.Second,
+	}
+}
+
+// DefaultDatabase is the MongoDB collection blogs are kept in by default.
+func DefaultDatabase() config.Database {
+	return config.Database{URI: "mongodb://localhost:27017", Name: "mydb", Collection: "blog"}
+}
+
+// Tokens loads the bearer tokens of c.TokensFile. Without a file every call
+// is anonymous, so blogs can be read but not written.
+func Tokens(c config.Blog) (auth.TokenTable, error) {
+	if c.TokensFile == "" {
+		log.Println("no -tokens file given, blogs can be read but not written")
+		return auth.TokenTable{}, nil
+	}
+	return auth.LoadTokens(c.TokensFile)
+}
+
+// Stores are the stores the blog services keep their data in.
+type Stores struct {
+	Blogs    store.BlogStore
+	History  store.HistoryStore
+	Comments store.CommentStore
+
+	client *mongo.Client // nil for the memory stores
+}
+
+// OpenStores opens the stores of c.Store. MongoDB stores keep blogs in the
+// collection of db and revisions and comments in collections named after it;
+// their indexes are created if they do not exist yet, the durations of their
+// commands are recorded in metrics.DefaultRegistry, and the commands of traced
+// calls get spans of tracing.DefaultTracer.
+func OpenStores(ctx context.Context, c config.Blog, db config.Database) (*Stores, error) {
+	switch c.Store {
+	case "mongo":
+		fmt.Println("Connecting to MongoDB...")
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(db.URI).SetMonitor(store.CommandMonitor(metrics.DefaultRegistry, tracing.DefaultTracer)))
+		if err != nil {
+			return nil, err
+		}
+		stores, err := openMongoStores(ctx, client.Database(db.Name), db.Collection)
+		if err != nil {
+			client.Disconnect(context.Background())
+			return nil, err
+		}
+		stores.client = client
+		return stores, nil
+	case "memory":
+		fmt.Println("Using in-memory blog store...")
+		return &Stores{
+			Blogs:    store.NewMemoryBlogStore(),
+			History:  store.NewMemoryHistoryStore(),
+			Comments: store.NewMemoryCommentStore(),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown store %q, want mongo or memory", c.Store)
+}
+
+// openMongoStores opens the stores in db. MongoDB creates the database and
+// the collections when they are first written to.
+func openMongoStores(ctx context.Context, db *mongo.Database, collection string) (*Stores, error) {
+	blogs := store.NewMongoBlogStore(db.Collection(collection))
+	if err := blogs.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %v", err)
+	}
+	history := store.NewMongoHistoryStore(db.Collection(collection + "_revisions"))
+	if err := history.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %v", err)
+	}
+	comments := store.NewMongoCommentStore(db.Collection(collection + "_comments"))
+	if err := comments.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	return &Stores{Blogs: blogs, History: history, Comments: comments}, nil
+}
+
+// Ping checks that the stores can be reached. The memory stores always can.
+func (s *Stores) Ping(ctx context.Context) error {
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Ping(ctx, readpref.Primary())
+}
+
+// Close disconnects from MongoDB, if the stores use it.
+func (s *Stores) Close(ctx context.Context) error {
+	if s.client == nil {
+		return nil
+	}
+	fmt.Println("Closing MongoDB Connection...")
+	return s.client.Disconnect(ctx)
+}
@@ -0,0 +1,78 @@
+package blogservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"google.golang.org/grpc/codes"
+)
+
+// caller returns the identity the auth interceptor found for the call, or an
+// Unauthenticated status error for anonymous calls.
+func caller(ctx context.Context) (*auth.Identity, error) {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, rpcerr.New(
+			codes.Unauthenticated,
+			"this call needs a bearer token",
+			rpcerr.Info(reasonUnauthenticated, errorDomain, nil),
+		)
+	}
+	return id, nil
+}
+
+// checkOwner returns a PermissionDenied status error unless id wrote the blog
+// or is an admin.
+func checkOwner(id *auth.Identity, data *models.BlogItem) error {
+	if isAuthor(id, data.AuthorID) {
+		return nil
+	}
+	return blogError(
+		codes.PermissionDenied,
+		reasonNotAuthor,
+		data.ID,
+		fmt.Sprintf("Blog with specified ID %v belongs to another author", data.ID.Hex()),
+	)
+}
+
+// checkCommentOwner returns a PermissionDenied status error unless id wrote
+// the comment or is an admin.
+func checkCommentOwner(id *auth.Identity, comment *models.Comment) error {
+	if isAuthor(id, comment.AuthorID) {
+		return nil
+	}
+	return rpcerr.New(
+		codes.PermissionDenied,
+		fmt.Sprintf("Comment with specified ID %v belongs to another author", comment.ID.Hex()),
+		rpcerr.Info(reasonNotAuthor, errorDomain, map[string]string{"comment_id": comment.ID.Hex()}),
+	)
+}
+
+// checkAdmin returns a PermissionDenied status error unless id is an admin.
+func checkAdmin(id *auth.Identity) error {
+	if id.IsAdmin() {
+		return nil
+	}
+	return rpcerr.New(
+		codes.PermissionDenied,
+		fmt.Sprintf("this call needs the %s role", auth.AdminRole),
+		rpcerr.Info(reasonNotAdmin, errorDomain, nil),
+	)
+}
+
+func isAuthor(id *auth.Identity, authorID string) bool {
+	return id.IsAdmin() || authorID == id.Subject
+}
+
+// authorFor returns the author recorded for a blog created by id: the caller
+// itself, unless an admin creates it on behalf of the requested author.
+func authorFor(id *auth.Identity, requested string) string {
+	if id.IsAdmin() && requested != "" {
+		return requested
+	}
+	return id.Subject
+}
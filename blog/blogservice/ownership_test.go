@@ -0,0 +1,136 @@
+package blogservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestServer_Ownership(t *testing.T) {
+	s := newTestServer()
+	khoi := auth.NewContext(context.Background(), &auth.Identity{Subject: "Khoi"})
+	john := auth.NewContext(context.Background(), &auth.Identity{Subject: "John"})
+
+	_, err := s.CreateBlog(context.Background(), &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "anonymous"}})
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Errorf("CreateBlog(anonymous) got code %v, want %v", code, codes.Unauthenticated)
+	}
+
+	createRes, err := s.CreateBlog(khoi, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{AuthorId: "John", Title: "mine"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blog := createRes.GetBlog()
+	if got := blog.GetAuthorId(); got != "Khoi" {
+		t.Errorf("CreateBlog() got author %q, want the caller Khoi", got)
+	}
+
+	_, err = s.UpdateBlog(john, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: blog.GetId(), Title: "stolen", Content: "stolen"}})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("UpdateBlog(non-owner) got code %v, want %v", code, codes.PermissionDenied)
+	}
+	_, err = s.DeleteBlog(john, &blogpb.DeleteBlogRequest{BlogId: blog.GetId()})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("DeleteBlog(non-owner) got code %v, want %v", code, codes.PermissionDenied)
+	}
+
+	updateRes, err := s.UpdateBlog(khoi, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: blog.GetId(), AuthorId: "John", Title: "edited", Content: "edited"}})
+	if err != nil {
+		t.Fatalf("UpdateBlog(owner) had unexpected error: %v", err)
+	}
+	if got := updateRes.GetBlog().GetAuthorId(); got != "Khoi" {
+		t.Errorf("UpdateBlog(owner) got author %q, want Khoi kept", got)
+	}
+
+	// handing the blog over by naming the author in the mask needs an admin
+	_, err = s.UpdateBlog(khoi, &blogpb.UpdateBlogRequest{
+		Blog:       &blogpb.Blog{Id: blog.GetId(), AuthorId: "John"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"author_id"}},
+	})
+	if code, reason := status.Code(err), rpcerr.ReasonOf(err); code != codes.PermissionDenied || reason != reasonNotAdmin {
+		t.Errorf("UpdateBlog(owner, author_id) got code %v reason %q, want %v %s", code, reason, codes.PermissionDenied, reasonNotAdmin)
+	}
+	readRes, err := s.ReadBlog(khoi, &blogpb.ReadBlogRequest{BlogId: blog.GetId()})
+	if err != nil {
+		t.Fatalf("ReadBlog() had unexpected error: %v", err)
+	}
+	if got := readRes.GetBlog().GetAuthorId(); got != "Khoi" {
+		t.Errorf("UpdateBlog(owner, author_id) left author %q, want Khoi kept", got)
+	}
+	updateRes, err = s.UpdateBlog(adminContext(), &blogpb.UpdateBlogRequest{
+		Blog:       &blogpb.Blog{Id: blog.GetId(), AuthorId: "John"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"author_id"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBlog(admin, author_id) had unexpected error: %v", err)
+	}
+	if got := updateRes.GetBlog().GetAuthorId(); got != "John" {
+		t.Errorf("UpdateBlog(admin, author_id) got author %q, want John", got)
+	}
+
+	if _, err := s.DeleteBlog(adminContext(), &blogpb.DeleteBlogRequest{BlogId: blog.GetId()}); err != nil {
+		t.Errorf("DeleteBlog(admin) had unexpected error: %v", err)
+	}
+}
+
+// racingStore is a BlogStore that runs afterGet once, right after the first
+// Get or GetMany, as another caller changing the blogs in the meantime would.
+type racingStore struct {
+	store.BlogStore
+	afterGet func()
+}
+
+func (s *racingStore) Get(ctx context.Context, id primitive.ObjectID) (*models.BlogItem, error) {
+	data, err := s.BlogStore.Get(ctx, id)
+	if f := s.afterGet; f != nil {
+		s.afterGet = nil
+		f()
+	}
+	return data, err
+}
+
+func (s *racingStore) GetMany(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*models.BlogItem, error) {
+	found, err := s.BlogStore.GetMany(ctx, ids)
+	if f := s.afterGet; f != nil {
+		s.afterGet = nil
+		f()
+	}
+	return found, err
+}
+
+func TestServer_DeleteBlogRacingHandover(t *testing.T) {
+	blogs := &racingStore{BlogStore: store.NewMemoryBlogStore()}
+	s := NewServer(blogs, store.NewMemoryHistoryStore(), store.NewMemoryCommentStore())
+	khoi := auth.NewContext(context.Background(), &auth.Identity{Subject: "Khoi"})
+
+	createRes, err := s.CreateBlog(khoi, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "mine", Content: "mine"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	oid, _ := primitive.ObjectIDFromHex(createRes.GetBlog().GetId())
+
+	// the blog is handed over to John after Khoi's delete checked the owner
+	blogs.afterGet = func() {
+		if _, err := blogs.BlogStore.Update(context.Background(), oid, bson.M{"author_id": "John"}, store.AnyRevision); err != nil {
+			t.Fatalf("Update() had unexpected error: %v", err)
+		}
+	}
+	_, err = s.DeleteBlog(khoi, &blogpb.DeleteBlogRequest{BlogId: oid.Hex()})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("DeleteBlog() racing a handover got code %v, want %v", code, codes.PermissionDenied)
+	}
+	if data, err := blogs.Get(context.Background(), oid); err != nil || !data.DeletedAt.IsZero() {
+		t.Errorf("DeleteBlog() racing a handover left %+v, %v, want John's blog outside the trash", data, err)
+	}
+}
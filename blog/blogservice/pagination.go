@@ -0,0 +1,150 @@
+package blogservice
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var errBadPageToken = errors.New("page_token is malformed or does not match the request")
+
+// pageToken is the decoded form of ListBlogsPageResponse.next_page_token.
+// It remembers the filters of the request that produced it so a token cannot
+// be replayed against a different listing.
+type pageToken struct {
+	After    string   `json:"after"`
+	AuthorID string   `json:"author_id,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Desc     bool     `json:"desc,omitempty"`
+	Trash    int32    `json:"trash,omitempty"`
+}
+
+func encodePageToken(last primitive.ObjectID, req *blogpb.ListBlogRequest) string {
+	bt, _ := json.Marshal(pageToken{
+		After:    last.Hex(),
+		AuthorID: req.GetAuthorId(),
+		Tags:     normalizeTags(req.GetTags()),
+		Desc:     req.GetSortOrder() == blogpb.ListBlogRequest_NEWEST_FIRST,
+		Trash:    int32(req.GetTrash()),
+	})
+	return base64.RawURLEncoding.EncodeToString(bt)
+}
+
+// commentPageToken is the decoded form of ListCommentsResponse.next_page_token.
+type commentPageToken struct {
+	After    string `json:"after"`
+	BlogID   string `json:"blog_id"`
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+func encodeCommentPageToken(last primitive.ObjectID, req *blogpb.ListCommentsRequest) string {
+	bt, _ := json.Marshal(commentPageToken{
+		After:    last.Hex(),
+		BlogID:   req.GetBlogId(),
+		ParentID: req.GetParentId(),
+	})
+	return base64.RawURLEncoding.EncodeToString(bt)
+}
+
+// decodeCommentPageToken returns the ID of the last comment on the previous
+// page, checking the token was issued for the same blog and parent.
+func decodeCommentPageToken(req *blogpb.ListCommentsRequest) (primitive.ObjectID, error) {
+	bt, err := base64.RawURLEncoding.DecodeString(req.GetPageToken())
+	if err != nil {
+		return primitive.NilObjectID, errBadPageToken
+	}
+	token := commentPageToken{}
+	if err := json.Unmarshal(bt, &token); err != nil {
+		return primitive.NilObjectID, errBadPageToken
+	}
+	if token.BlogID != req.GetBlogId() || token.ParentID != req.GetParentId() {
+		return primitive.NilObjectID, errBadPageToken
+	}
+	after, err := primitive.ObjectIDFromHex(token.After)
+	if err != nil {
+		return primitive.NilObjectID, errBadPageToken
+	}
+
+	return after, nil
+}
+
+// listOptions converts the request filters and page token into store.ListOptions.
+// The returned Limit is the requested page size, 0 meaning unlimited. Its
+// errors are InvalidArgument status errors about the offending field.
+func listOptions(req *blogpb.ListBlogRequest) (store.ListOptions, error) {
+	opts := store.ListOptions{
+		AuthorID:   req.GetAuthorId(),
+		Tags:       normalizeTags(req.GetTags()),
+		Descending: req.GetSortOrder() == blogpb.ListBlogRequest_NEWEST_FIRST,
+		Limit:      int(req.GetPageSize()),
+	}
+	if opts.Limit < 0 {
+		return opts, fieldError("page_size", errors.New("page_size must not be negative"))
+	}
+	switch req.GetTrash() {
+	case blogpb.ListBlogRequest_HIDE_TRASHED:
+		opts.Trash = store.HideTrashed
+	case blogpb.ListBlogRequest_ONLY_TRASHED:
+		opts.Trash = store.OnlyTrashed
+	case blogpb.ListBlogRequest_WITH_TRASHED:
+		opts.Trash = store.WithTrashed
+	default:
+		return opts, fieldError("trash", fmt.Errorf("unknown trash filter %v", req.GetTrash()))
+	}
+
+	if req.GetPageToken() == "" {
+		return opts, nil
+	}
+	after, err := decodePageToken(req, opts)
+	if err != nil {
+		return opts, fieldError("page_token", err)
+	}
+	opts.After = after
+
+	return opts, nil
+}
+
+// decodePageToken returns the ID of the last blog on the previous page,
+// checking the token was issued for the same filters as opts.
+func decodePageToken(req *blogpb.ListBlogRequest, opts store.ListOptions) (primitive.ObjectID, error) {
+	bt, err := base64.RawURLEncoding.DecodeString(req.GetPageToken())
+	if err != nil {
+		return primitive.NilObjectID, errBadPageToken
+	}
+	token := pageToken{}
+	if err := json.Unmarshal(bt, &token); err != nil {
+		return primitive.NilObjectID, errBadPageToken
+	}
+	if token.AuthorID != opts.AuthorID || !equalTags(token.Tags, opts.Tags) || token.Desc != opts.Descending || token.Trash != int32(req.GetTrash()) {
+		return primitive.NilObjectID, errBadPageToken
+	}
+	after, err := primitive.ObjectIDFromHex(token.After)
+	if err != nil {
+		return primitive.NilObjectID, errBadPageToken
+	}
+
+	return after, nil
+}
+
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package blogservice
+
+import (
+	"fmt"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/render"
+)
+
+// checkRenderFormat returns an InvalidArgument status error unless renderBlog
+// can convert content to format, so that requests are rejected before any
+// blog is read.
+func checkRenderFormat(format blogpb.RenderFormat) error {
+	switch format {
+	case blogpb.RenderFormat_RAW, blogpb.RenderFormat_HTML, blogpb.RenderFormat_PLAIN_TEXT:
+		return nil
+	}
+	return fieldError("render_format", fmt.Errorf("unknown render_format %v", format))
+}
+
+// renderBlog fills in the fields of blog computed from its Markdown content,
+// then converts the content to format.
+func renderBlog(blog *blogpb.Blog, format blogpb.RenderFormat) error {
+	plain := render.PlainText(blog.GetContent())
+	words := render.WordCount(plain)
+	blog.Excerpt = render.Excerpt(plain)
+	blog.WordCount = int32(words)
+	blog.ReadingTimeMinutes = int32(render.ReadingTime(words))
+
+	switch format {
+	case blogpb.RenderFormat_RAW:
+	case blogpb.RenderFormat_HTML:
+		html, err := render.HTML(blog.GetContent())
+		if err != nil {
+			return err
+		}
+		blog.Content = html
+	case blogpb.RenderFormat_PLAIN_TEXT:
+		blog.Content = plain
+	default:
+		return fmt.Errorf("unknown render_format %v", format)
+	}
+
+	return nil
+}
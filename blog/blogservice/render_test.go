@@ -0,0 +1,62 @@
+package blogservice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_ReadBlogRenderFormat(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	content := "# Hello\n\nSome *Markdown* with <script>alert(1)</script> in it."
+	created, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "render", Content: content}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	id := created.GetBlog().GetId()
+
+	tests := []struct {
+		format blogpb.RenderFormat
+		want   string
+	}{
+		{blogpb.RenderFormat_RAW, content},
+		{blogpb.RenderFormat_HTML, "<h1>Hello</h1>\n<p>Some <em>Markdown</em> with <!-- raw HTML omitted -->alert(1)<!-- raw HTML omitted --> in it.</p>\n"},
+		{blogpb.RenderFormat_PLAIN_TEXT, "Hello\nSome Markdown with alert(1) in it."},
+	}
+	for _, tt := range tests {
+		res, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: id, RenderFormat: tt.format})
+		if err != nil {
+			t.Fatalf("ReadBlog(%v) had unexpected error: %v", tt.format, err)
+		}
+		blog := res.GetBlog()
+		if blog.GetContent() != tt.want {
+			t.Errorf("ReadBlog(%v) got content %q, want %q", tt.format, blog.GetContent(), tt.want)
+		}
+		if blog.GetWordCount() != 7 || blog.GetReadingTimeMinutes() != 1 {
+			t.Errorf("ReadBlog(%v) got %d words and %d minutes, want 7 words and 1 minute", tt.format, blog.GetWordCount(), blog.GetReadingTimeMinutes())
+		}
+		if !strings.HasPrefix(blog.GetExcerpt(), "Hello Some Markdown") {
+			t.Errorf("ReadBlog(%v) got excerpt %q, want it to start with the plain text", tt.format, blog.GetExcerpt())
+		}
+	}
+
+	// an unknown format is the caller's mistake, reported before reading
+	_, err = s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: id, RenderFormat: 7})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("ReadBlog(7) got code %v, want %v", code, codes.InvalidArgument)
+	}
+	if got := rpcerr.ViolationsOf(err); len(got) != 1 || got[0].GetField() != "render_format" {
+		t.Errorf("ReadBlog(7) got violations %v, want one for render_format", got)
+	}
+	_, err = s.ListBlogsPage(ctx, &blogpb.ListBlogRequest{RenderFormat: 7})
+	if got := rpcerr.ViolationsOf(err); status.Code(err) != codes.InvalidArgument || len(got) != 1 || got[0].GetField() != "render_format" {
+		t.Errorf("ListBlogsPage(7) got %v with violations %v, want InvalidArgument for render_format", err, got)
+	}
+}
@@ -0,0 +1,426 @@
+// Package blogservice implements the BlogService and the CommentService on
+// top of the blog stores.
+package blogservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/search"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements blogpb.BlogServiceServer.
+type Server struct {
+	store    store.BlogStore
+	history  store.HistoryStore
+	comments store.CommentStore
+	feed     *changeFeed
+}
+
+// NewServer returns a Server keeping blogs in blogStore, their past versions
+// in history and the comments on them in comments.
+func NewServer(blogStore store.BlogStore, history store.HistoryStore, comments store.CommentStore) *Server {
+	return &Server{
+		store:    blogStore,
+		history:  history,
+		comments: comments,
+		feed:     newChangeFeed(),
+	}
+}
+
+// Services are the full names of the services Register registers, which
+// the health service reports the status of.
+var Services = []string{"blog.BlogService", "blog.CommentService"}
+
+// Register registers the BlogService and the CommentService on gs.
+func (s *Server) Register(gs *grpc.Server) {
+	blogpb.RegisterBlogServiceServer(gs, s)
+	blogpb.RegisterCommentServiceServer(gs, newCommentServer(s.comments, s))
+}
+
+func (s *Server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest) (*blogpb.CreateBlogResponse, error) {
+	blog := req.GetBlog()
+
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := now()
+	data := &models.BlogItem{
+		AuthorID:  authorFor(id, blog.GetAuthorId()),
+		Title:     blog.GetTitle(),
+		Content:   blog.GetContent(),
+		Tags:      normalizeTags(blog.GetTags()),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Revision:  1,
+	}
+
+	if err := s.store.Create(ctx, data); err != nil {
+		return nil, internalError(err)
+	}
+	s.feed.publish(blogpb.WatchBlogsResponse_CREATED, dataToBlogPb(data))
+
+	return &blogpb.CreateBlogResponse{
+		Blog: dataToBlogPb(data),
+	}, nil
+}
+
+func (s *Server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*blogpb.ReadBlogResponse, error) {
+	oid, err := parseBlogID(req.GetBlogId())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRenderFormat(req.GetRenderFormat()); err != nil {
+		return nil, err
+	}
+
+	data, err := s.store.Get(ctx, oid)
+	if err != nil {
+		return nil, storeError(err, oid)
+	}
+	if !data.DeletedAt.IsZero() {
+		if !req.GetShowDeleted() {
+			return nil, storeError(store.ErrNotFound, oid)
+		}
+		id, err := caller(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkOwner(id, data); err != nil {
+			return nil, err
+		}
+	}
+
+	blog := dataToBlogPb(data)
+	if err := renderBlog(blog, req.GetRenderFormat()); err != nil {
+		return nil, internalError(err)
+	}
+
+	return &blogpb.ReadBlogResponse{
+		Blog: blog,
+	}, nil
+}
+
+func dataToBlogPb(data *models.BlogItem) *blogpb.Blog {
+	return &blogpb.Blog{
+		Id:        data.ID.Hex(),
+		AuthorId:  data.AuthorID,
+		Title:     data.Title,
+		Content:   data.Content,
+		Tags:      data.Tags,
+		CreatedAt: timeToPb(data.CreatedAt),
+		UpdatedAt: timeToPb(data.UpdatedAt),
+		Revision:  data.Revision,
+		DeletedAt: timeToPb(data.DeletedAt),
+	}
+}
+
+// timeToPb converts t to a protobuf Timestamp, leaving it unset for the zero
+// time of blogs written before timestamps were recorded.
+func timeToPb(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// pbToTime converts a protobuf Timestamp sent by a client to the millisecond
+// precision MongoDB stores, returning the zero time when it is unset.
+func pbToTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime().UTC().Truncate(time.Millisecond)
+}
+
+// now returns the current time at the millisecond precision MongoDB stores,
+// so a blog reads back exactly as it was returned when written.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// parseBlogID parses a blog ID sent by a client, returning an
+// InvalidArgument status error if it is not a valid ObjectID.
+func parseBlogID(blogID string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(blogID)
+	if err != nil {
+		return oid, invalidIDError("blog_id", err)
+	}
+
+	return oid, nil
+}
+
+// storeError converts an error returned by the BlogStore into a gRPC status error.
+func storeError(err error, oid primitive.ObjectID) error {
+	switch err {
+	case store.ErrNotFound:
+		return notFoundError(
+			blogResource,
+			oid.Hex(),
+			fmt.Sprintf("Cannot find blog with specified ID: %v", oid.Hex()),
+		)
+	case store.ErrRevisionMismatch:
+		return blogError(
+			codes.Aborted,
+			reasonRevisionMismatch,
+			oid,
+			fmt.Sprintf("Blog with specified ID %v was modified concurrently, read it again and retry", oid.Hex()),
+		)
+	}
+
+	return blogError(
+		codes.Internal,
+		reasonInternal,
+		oid,
+		fmt.Sprintf("Internal error for blog with specified ID %v: %v", oid.Hex(), err),
+	)
+}
+
+func (s *Server) UpdateBlog(ctx context.Context, req *blogpb.UpdateBlogRequest) (*blogpb.UpdateBlogResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	blog := req.GetBlog()
+	oid, err := parseBlogID(blog.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := updateSet(blog, req.GetUpdateMask())
+	if err != nil {
+		return nil, fieldError("update_mask", err)
+	}
+	if err := requiredUpdates(req); err != nil {
+		return nil, err
+	}
+	set["updated_at"] = now()
+
+	// only admins hand blogs over to other authors, and updates of every
+	// field keep the author of the blog unless an admin changes it
+	if masks(req.GetUpdateMask(), "author_id") {
+		if err := checkAdmin(id); err != nil {
+			return nil, err
+		}
+	}
+	if author, ok := set["author_id"]; ok && (!id.IsAdmin() || author == "") {
+		delete(set, "author_id")
+	}
+
+	// blogs in the trash have to be restored before they can be edited
+	data, err := s.updateWithHistory(ctx, id, oid, set, req.GetExpectedRevision())
+	if err != nil {
+		return nil, err
+	}
+	s.feed.publish(blogpb.WatchBlogsResponse_UPDATED, dataToBlogPb(data))
+
+	return &blogpb.UpdateBlogResponse{
+		Blog: dataToBlogPb(data),
+	}, nil
+}
+
+func (s *Server) DeleteBlog(ctx context.Context, req *blogpb.DeleteBlogRequest) (*blogpb.DeleteBlogResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oid, err := parseBlogID(req.GetBlogId())
+	if err != nil {
+		return nil, err
+	}
+
+	// move the blog to the trash, PurgeBlog or the purger remove it for good.
+	// Like updateWithHistory, the write is conditional on the version checked.
+	var data *models.BlogItem
+	for attempt := 1; ; attempt++ {
+		prior, err := s.activeBlog(ctx, oid)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkOwner(id, prior); err != nil {
+			return nil, err
+		}
+		if req.GetExpectedRevision() != store.AnyRevision && req.GetExpectedRevision() != prior.Revision {
+			return nil, storeError(store.ErrRevisionMismatch, oid)
+		}
+
+		now := now()
+		set := bson.M{"deleted_at": now, "updated_at": now}
+		data, err = s.store.Update(ctx, oid, set, prior.Revision)
+		if err == store.ErrRevisionMismatch && req.GetExpectedRevision() == store.AnyRevision && attempt < maxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, storeError(err, oid)
+		}
+		break
+	}
+	s.feed.publish(blogpb.WatchBlogsResponse_DELETED, dataToBlogPb(data))
+
+	return &blogpb.DeleteBlogResponse{
+		BlogId: oid.Hex(),
+	}, nil
+}
+
+func (s *Server) ListBlog(req *blogpb.ListBlogRequest, stream blogpb.BlogService_ListBlogServer) error {
+	if err := checkRenderFormat(req.GetRenderFormat()); err != nil {
+		return err
+	}
+	opts, err := listOptions(req)
+	if err != nil {
+		return err
+	}
+	if err := restrictTrash(stream.Context(), &opts); err != nil {
+		return err
+	}
+
+	err = s.store.List(stream.Context(), opts, func(data *models.BlogItem) error {
+		blog := dataToBlogPb(data)
+		if err := renderBlog(blog, req.GetRenderFormat()); err != nil {
+			return err
+		}
+		return stream.Send(&blogpb.ListBlogResponse{Blog: blog})
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	return nil
+}
+
+func (s *Server) ListBlogsPage(ctx context.Context, req *blogpb.ListBlogRequest) (*blogpb.ListBlogsPageResponse, error) {
+	if err := checkRenderFormat(req.GetRenderFormat()); err != nil {
+		return nil, err
+	}
+	opts, err := listOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := restrictTrash(ctx, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultPageSize
+	}
+	if opts.Limit > maxPageSize {
+		opts.Limit = maxPageSize
+	}
+
+	// fetch one extra blog to find out whether there is a next page
+	pageSize := opts.Limit
+	opts.Limit++
+
+	res := &blogpb.ListBlogsPageResponse{}
+	var last primitive.ObjectID
+	err = s.store.List(ctx, opts, func(data *models.BlogItem) error {
+		if len(res.Blogs) == pageSize {
+			res.NextPageToken = encodePageToken(last, req)
+			return nil
+		}
+		blog := dataToBlogPb(data)
+		if err := renderBlog(blog, req.GetRenderFormat()); err != nil {
+			return err
+		}
+		res.Blogs = append(res.Blogs, blog)
+		last = data.ID
+		return nil
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return res, nil
+}
+
+func (s *Server) SearchBlogs(ctx context.Context, req *blogpb.SearchBlogsRequest) (*blogpb.SearchBlogsResponse, error) {
+	query := req.GetQuery()
+	if len(search.Terms(query)) == 0 {
+		return nil, fieldError("query", errors.New("query must contain at least one word"))
+	}
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	hits, err := s.store.Search(ctx, query, limit)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	res := &blogpb.SearchBlogsResponse{}
+	for _, hit := range hits {
+		snippets := search.Snippets(hit.Item.Title, query, maxSnippets)
+		snippets = append(snippets, search.Snippets(hit.Item.Content, query, maxSnippets-len(snippets))...)
+		res.Results = append(res.Results, &blogpb.SearchResult{
+			Blog:     dataToBlogPb(hit.Item),
+			Score:    hit.Score,
+			Snippets: snippets,
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Server) WatchBlogs(req *blogpb.WatchBlogsRequest, stream blogpb.BlogService_WatchBlogsServer) error {
+	w, backlog, err := s.feed.subscribe(req.GetResumeToken())
+	switch err {
+	case nil:
+	case errResumeTokenExpired:
+		return rpcerr.New(
+			codes.OutOfRange,
+			err.Error(),
+			rpcerr.Info(reasonResumeTokenExpired, errorDomain, nil),
+		)
+	default:
+		return fieldError("resume_token", err)
+	}
+	defer s.feed.unsubscribe(w)
+
+	for _, event := range backlog {
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-w.events:
+			if !ok {
+				return rpcerr.New(
+					codes.ResourceExhausted,
+					"watcher fell behind, resume from the last resume_token received",
+					rpcerr.Info(reasonWatcherTooSlow, errorDomain, nil),
+				)
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+const (
+	defaultSearchLimit = 10
+	maxSnippets        = 3
+)
@@ -0,0 +1,338 @@
+package blogservice
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// newTestServer returns a server backed by in-memory stores.
+func newTestServer() *Server {
+	return NewServer(store.NewMemoryBlogStore(), store.NewMemoryHistoryStore(), store.NewMemoryCommentStore())
+}
+
+// adminContext returns the context of a call made by an admin, which may
+// change any blog.
+func adminContext() context.Context {
+	return auth.NewContext(context.Background(), &auth.Identity{Subject: "admin", Roles: []string{auth.AdminRole}})
+}
+
+func TestServer_CRUD(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	createRes, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{
+		AuthorId: "Khoi",
+		Title:    "My first blog",
+		Content:  "Content of the first blog",
+	}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blogID := createRes.GetBlog().GetId()
+	if blogID == "" {
+		t.Fatalf("CreateBlog() returned a blog without an ID")
+	}
+	if got := createRes.GetBlog().GetRevision(); got != 1 {
+		t.Errorf("CreateBlog() got revision %d, want 1", got)
+	}
+	if createRes.GetBlog().GetCreatedAt() == nil || createRes.GetBlog().GetUpdatedAt() == nil {
+		t.Errorf("CreateBlog() got created_at %v and updated_at %v, want both set", createRes.GetBlog().GetCreatedAt(), createRes.GetBlog().GetUpdatedAt())
+	}
+
+	readRes, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: blogID})
+	if err != nil {
+		t.Fatalf("ReadBlog(%v) had unexpected error: %v", blogID, err)
+	}
+	if got, want := readRes.GetBlog().GetTitle(), "My first blog"; got != want {
+		t.Errorf("ReadBlog(%v) got title %q, want %q", blogID, got, want)
+	}
+
+	updateRes, err := s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{
+		Id:       blogID,
+		AuthorId: "Changed Author",
+		Title:    "My First Blog (edited)",
+		Content:  "Content of my first blog, with new additions",
+	}})
+	if err != nil {
+		t.Fatalf("UpdateBlog(%v) had unexpected error: %v", blogID, err)
+	}
+	if got, want := updateRes.GetBlog().GetAuthorId(), "Changed Author"; got != want {
+		t.Errorf("UpdateBlog(%v) got author %q, want %q", blogID, got, want)
+	}
+	if got := updateRes.GetBlog().GetRevision(); got != 2 {
+		t.Errorf("UpdateBlog(%v) got revision %d, want 2", blogID, got)
+	}
+	if got, want := updateRes.GetBlog().GetCreatedAt().AsTime(), createRes.GetBlog().GetCreatedAt().AsTime(); !got.Equal(want) {
+		t.Errorf("UpdateBlog(%v) got created_at %v, want unchanged %v", blogID, got, want)
+	}
+
+	if _, err := s.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: blogID}); err != nil {
+		t.Fatalf("DeleteBlog(%v) had unexpected error: %v", blogID, err)
+	}
+
+	_, err = s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: blogID})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("ReadBlog(%v) after delete got code %v, want %v", blogID, got, codes.NotFound)
+	}
+}
+
+func TestServer_InvalidID(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	_, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: "not-an-id"})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("ReadBlog(not-an-id) got code %v, want %v", got, codes.InvalidArgument)
+	}
+
+	_, err = s.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: "5bdc29e661b75adcac496cf4"})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("DeleteBlog(missing) got code %v, want %v", got, codes.NotFound)
+	}
+}
+
+func TestServer_ListBlogsPage(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	for _, author := range []string{"Khoi", "John", "Khoi", "Khoi", "Khoi"} {
+		if _, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{AuthorId: author}}); err != nil {
+			t.Fatalf("CreateBlog() had unexpected error: %v", err)
+		}
+	}
+
+	req := &blogpb.ListBlogRequest{PageSize: 3, AuthorId: "Khoi", SortOrder: blogpb.ListBlogRequest_NEWEST_FIRST}
+	first, err := s.ListBlogsPage(ctx, req)
+	if err != nil {
+		t.Fatalf("ListBlogsPage() had unexpected error: %v", err)
+	}
+	if len(first.GetBlogs()) != 3 || first.GetNextPageToken() == "" {
+		t.Fatalf("ListBlogsPage() got %d blogs and token %q, want 3 blogs and a token", len(first.GetBlogs()), first.GetNextPageToken())
+	}
+
+	req.PageToken = first.GetNextPageToken()
+	second, err := s.ListBlogsPage(ctx, req)
+	if err != nil {
+		t.Fatalf("ListBlogsPage(token) had unexpected error: %v", err)
+	}
+	if len(second.GetBlogs()) != 1 || second.GetNextPageToken() != "" {
+		t.Errorf("ListBlogsPage(token) got %d blogs and token %q, want 1 blog and no token", len(second.GetBlogs()), second.GetNextPageToken())
+	}
+
+	seen := map[string]bool{}
+	for _, blog := range append(first.GetBlogs(), second.GetBlogs()...) {
+		if blog.GetAuthorId() != "Khoi" {
+			t.Errorf("ListBlogsPage() returned blog by %q, want only Khoi", blog.GetAuthorId())
+		}
+		if seen[blog.GetId()] {
+			t.Errorf("ListBlogsPage() returned blog %v twice", blog.GetId())
+		}
+		seen[blog.GetId()] = true
+	}
+
+	// a token cannot be reused with different filters
+	req.AuthorId = "John"
+	_, err = s.ListBlogsPage(ctx, req)
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("ListBlogsPage(mismatched token) got code %v, want %v", got, codes.InvalidArgument)
+	}
+
+	// each error is reported against the field that caused it
+	fieldTests := []struct {
+		name  string
+		req   *blogpb.ListBlogRequest
+		field string
+	}{
+		{"mismatched token", req, "page_token"},
+		{"negative page_size", &blogpb.ListBlogRequest{PageSize: -1}, "page_size"},
+		{"unknown trash filter", &blogpb.ListBlogRequest{Trash: 7}, "trash"},
+	}
+	for _, tt := range fieldTests {
+		_, err := s.ListBlogsPage(ctx, tt.req)
+		if got := rpcerr.ViolationsOf(err); len(got) != 1 || got[0].GetField() != tt.field {
+			t.Errorf("ListBlogsPage(%s) got violations %v, want one for %s", tt.name, got, tt.field)
+		}
+	}
+}
+
+func TestServer_OptimisticConcurrency(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	createRes, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "v1", Content: "c"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blog := createRes.GetBlog()
+
+	blog.Title = "v2"
+	if _, err := s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: blog, ExpectedRevision: 1}); err != nil {
+		t.Fatalf("UpdateBlog(expected 1) had unexpected error: %v", err)
+	}
+
+	// a second editor still holding revision 1 must not overwrite v2
+	blog.Title = "stale"
+	_, err = s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: blog, ExpectedRevision: 1})
+	if got := status.Code(err); got != codes.Aborted {
+		t.Errorf("UpdateBlog(stale revision) got code %v, want %v", got, codes.Aborted)
+	}
+
+	_, err = s.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: blog.GetId(), ExpectedRevision: 1})
+	if got := status.Code(err); got != codes.Aborted {
+		t.Errorf("DeleteBlog(stale revision) got code %v, want %v", got, codes.Aborted)
+	}
+	if _, err := s.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: blog.GetId(), ExpectedRevision: 2}); err != nil {
+		t.Errorf("DeleteBlog(expected 2) had unexpected error: %v", err)
+	}
+}
+
+func TestServer_UpdateBlogWithMask(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	createRes, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{
+		AuthorId: "Khoi",
+		Title:    "My first blog",
+		Content:  "Content of the first blog",
+	}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blogID := createRes.GetBlog().GetId()
+
+	// only the title is sent, content and author must be kept
+	updateRes, err := s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{
+		Blog:       &blogpb.Blog{Id: blogID, Title: "New title"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBlog(title) had unexpected error: %v", err)
+	}
+	got := updateRes.GetBlog()
+	if got.GetTitle() != "New title" || got.GetContent() != "Content of the first blog" || got.GetAuthorId() != "Khoi" {
+		t.Errorf("UpdateBlog(title) got %v, want only the title changed", got)
+	}
+	if got.GetRevision() != 2 {
+		t.Errorf("UpdateBlog(title) got revision %d, want 2", got.GetRevision())
+	}
+
+	_, err = s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{
+		Blog:       &blogpb.Blog{Id: blogID},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"revision"}},
+	})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("UpdateBlog(revision) got code %v, want %v", code, codes.InvalidArgument)
+	}
+}
+
+// Updates must not blank the fields CreateBlog requires, whether their mask
+// names them or is empty and so covers every field.
+func TestServer_UpdateBlogRequiredFields(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	createRes, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "Title", Content: "Content"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blogID := createRes.GetBlog().GetId()
+
+	tests := []struct {
+		name string
+		req  *blogpb.UpdateBlogRequest
+		want []string
+	}{
+		{"empty mask", &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: blogID, Title: "New title"}}, []string{"blog.content"}},
+		{"masked title", &blogpb.UpdateBlogRequest{
+			Blog:       &blogpb.Blog{Id: blogID, Content: "New content"},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title", "content"}},
+		}, []string{"blog.title"}},
+	}
+
+	for _, tt := range tests {
+		_, err := s.UpdateBlog(ctx, tt.req)
+		if code := status.Code(err); code != codes.InvalidArgument {
+			t.Errorf("UpdateBlog(%s) got code %v, want %v", tt.name, code, codes.InvalidArgument)
+		}
+		var got []string
+		for _, v := range rpcerr.ViolationsOf(err) {
+			got = append(got, v.GetField())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("UpdateBlog(%s) got violations of %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// a mask leaving the required fields out keeps them
+	updateRes, err := s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{
+		Blog:       &blogpb.Blog{Id: blogID, Tags: []string{"go"}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"tags"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBlog(tags) had unexpected error: %v", err)
+	}
+	if got := updateRes.GetBlog(); got.GetTitle() != "Title" || got.GetContent() != "Content" || got.GetRevision() != 2 {
+		t.Errorf("UpdateBlog(tags) got %v, want title and content kept at revision 2", got)
+	}
+}
+
+func TestServer_SearchBlogs(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	for _, blog := range []*blogpb.Blog{
+		{Title: "Learning gRPC", Content: "Unary calls first"},
+		{Title: "Notes", Content: "Streaming with gRPC is neat"},
+		{Title: "Cooking", Content: "Pasta"},
+	} {
+		if _, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: blog}); err != nil {
+			t.Fatalf("CreateBlog() had unexpected error: %v", err)
+		}
+	}
+
+	res, err := s.SearchBlogs(ctx, &blogpb.SearchBlogsRequest{Query: "grpc"})
+	if err != nil {
+		t.Fatalf("SearchBlogs(grpc) had unexpected error: %v", err)
+	}
+	if len(res.GetResults()) != 2 {
+		t.Fatalf("SearchBlogs(grpc) got %d results, want 2", len(res.GetResults()))
+	}
+	top := res.GetResults()[0]
+	if top.GetBlog().GetTitle() != "Learning gRPC" {
+		t.Errorf("SearchBlogs(grpc) top result %q, want the title match", top.GetBlog().GetTitle())
+	}
+	if len(top.GetSnippets()) == 0 || top.GetSnippets()[0] != "Learning <em>gRPC</em>" {
+		t.Errorf("SearchBlogs(grpc) got snippets %q, want the highlighted title first", top.GetSnippets())
+	}
+
+	_, err = s.SearchBlogs(ctx, &blogpb.SearchBlogsRequest{Query: "  ?! "})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("SearchBlogs(no words) got code %v, want %v", code, codes.InvalidArgument)
+	}
+}
+
+func TestServer_Register(t *testing.T) {
+	gs := grpc.NewServer()
+	newTestServer().Register(gs)
+
+	info := gs.GetServiceInfo()
+	if len(info) != len(Services) {
+		t.Errorf("Register() registered %d services, want the %d of Services", len(info), len(Services))
+	}
+	for _, name := range Services {
+		if _, ok := info[name]; !ok {
+			t.Errorf("Register() did not register %s", name)
+		}
+	}
+}
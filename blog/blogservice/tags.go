@@ -0,0 +1,39 @@
+package blogservice
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+)
+
+// normalizeTags lowercases and trims tags, dropping empty and repeated ones,
+// so "Go" and "go " end up in the same per-tag feed.
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+func (s *Server) ListTags(ctx context.Context, req *blogpb.ListTagsRequest) (*blogpb.ListTagsResponse, error) {
+	tags, err := s.store.Tags(ctx, int(req.GetLimit()))
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	res := &blogpb.ListTagsResponse{}
+	for _, tag := range tags {
+		res.Tags = append(res.Tags, &blogpb.TagCount{Tag: tag.Tag, Count: int64(tag.Count)})
+	}
+
+	return res, nil
+}
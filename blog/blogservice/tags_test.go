@@ -0,0 +1,68 @@
+package blogservice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := normalizeTags([]string{" Go", "go", "", "gRPC ", "  "})
+	if want := []string{"go", "grpc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeTags() got %q, want %q", got, want)
+	}
+}
+
+func TestServer_Tags(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	for _, blog := range []*blogpb.Blog{
+		{Title: "a", Tags: []string{"Go", "gRPC"}},
+		{Title: "b", Tags: []string{"grpc"}},
+		{Title: "c"},
+	} {
+		if _, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: blog}); err != nil {
+			t.Fatalf("CreateBlog(%q) had unexpected error: %v", blog.GetTitle(), err)
+		}
+	}
+
+	res, err := s.ListBlogsPage(ctx, &blogpb.ListBlogRequest{Tags: []string{"GRPC"}, PageSize: 1})
+	if err != nil {
+		t.Fatalf("ListBlogsPage(tags) had unexpected error: %v", err)
+	}
+	if len(res.GetBlogs()) != 1 || res.GetBlogs()[0].GetTitle() != "a" {
+		t.Fatalf("ListBlogsPage(tags) got %v, want blog a", res.GetBlogs())
+	}
+	if got, want := res.GetBlogs()[0].GetTags(), []string{"go", "grpc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListBlogsPage(tags) got tags %q, want %q", got, want)
+	}
+
+	_, err = s.ListBlogsPage(ctx, &blogpb.ListBlogRequest{Tags: []string{"go"}, PageSize: 1, PageToken: res.GetNextPageToken()})
+	if err == nil {
+		t.Errorf("ListBlogsPage() with a page_token for other tags got no error")
+	}
+	res, err = s.ListBlogsPage(ctx, &blogpb.ListBlogRequest{Tags: []string{"grpc"}, PageSize: 1, PageToken: res.GetNextPageToken()})
+	if err != nil {
+		t.Fatalf("ListBlogsPage(tags, page 2) had unexpected error: %v", err)
+	}
+	if len(res.GetBlogs()) != 1 || res.GetBlogs()[0].GetTitle() != "b" {
+		t.Errorf("ListBlogsPage(tags, page 2) got %v, want blog b", res.GetBlogs())
+	}
+
+	tagsRes, err := s.ListTags(ctx, &blogpb.ListTagsRequest{})
+	if err != nil {
+		t.Fatalf("ListTags() had unexpected error: %v", err)
+	}
+	var got []string
+	for _, tag := range tagsRes.GetTags() {
+		got = append(got, tag.GetTag())
+	}
+	if want := []string{"grpc", "go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListTags() got %q, want %q", got, want)
+	}
+	if count := tagsRes.GetTags()[0].GetCount(); count != 2 {
+		t.Errorf("ListTags() got count %d for grpc, want 2", count)
+	}
+}
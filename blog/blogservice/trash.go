@@ -0,0 +1,229 @@
+package blogservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+)
+
+// activeBlog returns the blog with the given ID, or a NotFound status error if
+// it does not exist or is in the trash.
+func (s *Server) activeBlog(ctx context.Context, oid primitive.ObjectID) (*models.BlogItem, error) {
+	data, err := s.store.Get(ctx, oid)
+	if err != nil {
+		return nil, storeError(err, oid)
+	}
+	if !data.DeletedAt.IsZero() {
+		return nil, storeError(store.ErrNotFound, oid)
+	}
+
+	return data, nil
+}
+
+// readableBlog returns the blog with the given ID like activeBlog, except
+// that the author of a trashed blog and admins can still read it.
+func (s *Server) readableBlog(ctx context.Context, oid primitive.ObjectID) (*models.BlogItem, error) {
+	data, err := s.store.Get(ctx, oid)
+	if err != nil {
+		return nil, storeError(err, oid)
+	}
+	if !data.DeletedAt.IsZero() && !seesTrashOf(ctx, data.AuthorID) {
+		return nil, storeError(store.ErrNotFound, oid)
+	}
+
+	return data, nil
+}
+
+// seesTrashOf reports whether the caller of ctx may see the trashed blogs of
+// authorID: only the author and admins can.
+func seesTrashOf(ctx context.Context, authorID string) bool {
+	id, ok := auth.FromContext(ctx)
+	return ok && isAuthor(id, authorID)
+}
+
+// restrictTrash limits a listing that includes trashed blogs to the blogs of
+// the caller, unless it is an admin. It returns an Unauthenticated status
+// error for anonymous callers, and a PermissionDenied one when the listing
+// asks for the blogs of another author.
+func restrictTrash(ctx context.Context, opts *store.ListOptions) error {
+	if opts.Trash == store.HideTrashed {
+		return nil
+	}
+	id, err := caller(ctx)
+	if err != nil {
+		return err
+	}
+	if id.IsAdmin() {
+		return nil
+	}
+	if opts.AuthorID == "" {
+		opts.AuthorID = id.Subject
+	}
+	if opts.AuthorID != id.Subject {
+		return rpcerr.New(
+			codes.PermissionDenied,
+			fmt.Sprintf("the trashed blogs of author %v can only be listed by the author", opts.AuthorID),
+			rpcerr.Info(reasonNotAuthor, errorDomain, map[string]string{"author_id": opts.AuthorID}),
+		)
+	}
+
+	return nil
+}
+
+// trashedBlog returns the blog with the given ID, or a NotFound status error if
+// it does not exist or is not in the trash.
+func (s *Server) trashedBlog(ctx context.Context, oid primitive.ObjectID) (*models.BlogItem, error) {
+	data, err := s.store.Get(ctx, oid)
+	if err != nil {
+		return nil, storeError(err, oid)
+	}
+	if data.DeletedAt.IsZero() {
+		msg := fmt.Sprintf("Blog with specified ID %v is not in the trash", oid.Hex())
+		return nil, rpcerr.New(
+			codes.NotFound,
+			msg,
+			rpcerr.Info(reasonNotInTrash, errorDomain, map[string]string{"blog_id": oid.Hex()}),
+			rpcerr.Resource(blogResource, oid.Hex(), msg),
+		)
+	}
+
+	return data, nil
+}
+
+func (s *Server) RestoreBlog(ctx context.Context, req *blogpb.RestoreBlogRequest) (*blogpb.RestoreBlogResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oid, err := parseBlogID(req.GetBlogId())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.trashedBlog(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOwner(id, data); err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"deleted_at": nil, "updated_at": now()}
+	if data, err = s.store.Update(ctx, oid, set, data.Revision); err != nil {
+		return nil, storeError(err, oid)
+	}
+	s.feed.publish(blogpb.WatchBlogsResponse_RESTORED, dataToBlogPb(data))
+
+	return &blogpb.RestoreBlogResponse{
+		Blog: dataToBlogPb(data),
+	}, nil
+}
+
+func (s *Server) PurgeBlog(ctx context.Context, req *blogpb.PurgeBlogRequest) (*blogpb.PurgeBlogResponse, error) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oid, err := parseBlogID(req.GetBlogId())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.trashedBlog(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOwner(id, data); err != nil {
+		return nil, err
+	}
+	if err := s.purge(ctx, data); err != nil {
+		return nil, storeError(err, oid)
+	}
+
+	return &blogpb.PurgeBlogResponse{
+		BlogId: oid.Hex(),
+	}, nil
+}
+
+// purge permanently removes a trashed blog with its history and comments,
+// unless the blog changed since it was read.
+func (s *Server) purge(ctx context.Context, data *models.BlogItem) error {
+	data, err := s.store.Delete(ctx, data.ID, data.Revision)
+	if err != nil {
+		return err
+	}
+	if err := s.history.DeleteAll(ctx, data.ID); err != nil {
+		return err
+	}
+	if err := s.comments.DeleteByBlog(ctx, data.ID); err != nil {
+		return err
+	}
+	s.feed.publish(blogpb.WatchBlogsResponse_PURGED, dataToBlogPb(data))
+
+	return nil
+}
+
+// purgeTrash permanently removes the blogs that have been in the trash for
+// longer than retention and returns how many were removed.
+func (s *Server) purgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	var expired []*models.BlogItem
+	opts := store.ListOptions{
+		Trash:         store.OnlyTrashed,
+		DeletedBefore: now().Add(-retention),
+	}
+	err := s.store.List(ctx, opts, func(data *models.BlogItem) error {
+		expired = append(expired, data)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, data := range expired {
+		switch err := s.purge(ctx, data); err {
+		case nil:
+			purged++
+		case store.ErrNotFound, store.ErrRevisionMismatch:
+			// purged or restored by someone else in the meantime
+		default:
+			return purged, err
+		}
+	}
+
+	return purged, nil
+}
+
+// RunPurger calls purgeTrash every interval until ctx is done.
+func (s *Server) RunPurger(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.purgeTrash(ctx, retention)
+			if err != nil {
+				log.Printf("failed to purge the trash: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("purged %d blogs from the trash", purged)
+			}
+		}
+	}
+}
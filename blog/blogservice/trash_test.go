@@ -0,0 +1,208 @@
+package blogservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func listIDs(t *testing.T, ctx context.Context, s *Server, trash blogpb.ListBlogRequest_Trash) []string {
+	t.Helper()
+
+	res, err := s.ListBlogsPage(ctx, &blogpb.ListBlogRequest{Trash: trash})
+	if err != nil {
+		t.Fatalf("ListBlogsPage(%v) had unexpected error: %v", trash, err)
+	}
+	var ids []string
+	for _, blog := range res.GetBlogs() {
+		ids = append(ids, blog.GetId())
+	}
+	return ids
+}
+
+func TestServer_TrashAndRestore(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	var ids []string
+	for _, title := range []string{"keep", "trash"} {
+		res, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: title}})
+		if err != nil {
+			t.Fatalf("CreateBlog(%q) had unexpected error: %v", title, err)
+		}
+		ids = append(ids, res.GetBlog().GetId())
+	}
+	trashed := ids[1]
+
+	if _, err := s.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: trashed}); err != nil {
+		t.Fatalf("DeleteBlog() had unexpected error: %v", err)
+	}
+
+	_, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: trashed})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("ReadBlog(trashed) got code %v, want %v", code, codes.NotFound)
+	}
+	readRes, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: trashed, ShowDeleted: true})
+	if err != nil {
+		t.Fatalf("ReadBlog(trashed, show_deleted) had unexpected error: %v", err)
+	}
+	if readRes.GetBlog().GetDeletedAt() == nil {
+		t.Errorf("ReadBlog(trashed, show_deleted) got no deleted_at")
+	}
+
+	if got := listIDs(t, ctx, s, blogpb.ListBlogRequest_HIDE_TRASHED); len(got) != 1 || got[0] != ids[0] {
+		t.Errorf("ListBlogsPage(HIDE_TRASHED) got %v, want [%v]", got, ids[0])
+	}
+	if got := listIDs(t, ctx, s, blogpb.ListBlogRequest_ONLY_TRASHED); len(got) != 1 || got[0] != trashed {
+		t.Errorf("ListBlogsPage(ONLY_TRASHED) got %v, want [%v]", got, trashed)
+	}
+	if got := listIDs(t, ctx, s, blogpb.ListBlogRequest_WITH_TRASHED); len(got) != 2 {
+		t.Errorf("ListBlogsPage(WITH_TRASHED) got %v, want both blogs", got)
+	}
+
+	_, err = s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: trashed, Title: "edited", Content: "edited"}})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("UpdateBlog(trashed) got code %v, want %v", code, codes.NotFound)
+	}
+
+	restoreRes, err := s.RestoreBlog(ctx, &blogpb.RestoreBlogRequest{BlogId: trashed})
+	if err != nil {
+		t.Fatalf("RestoreBlog() had unexpected error: %v", err)
+	}
+	if restoreRes.GetBlog().GetDeletedAt() != nil {
+		t.Errorf("RestoreBlog() got deleted_at %v, want unset", restoreRes.GetBlog().GetDeletedAt())
+	}
+	if _, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: trashed}); err != nil {
+		t.Errorf("ReadBlog(restored) had unexpected error: %v", err)
+	}
+
+	_, err = s.RestoreBlog(ctx, &blogpb.RestoreBlogRequest{BlogId: trashed})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("RestoreBlog(not trashed) got code %v, want %v", code, codes.NotFound)
+	}
+	_, err = s.PurgeBlog(ctx, &blogpb.PurgeBlogRequest{BlogId: trashed})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("PurgeBlog(not trashed) got code %v, want %v", code, codes.NotFound)
+	}
+}
+
+func TestServer_PurgeTrash(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		res, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{}})
+		if err != nil {
+			t.Fatalf("CreateBlog() had unexpected error: %v", err)
+		}
+		ids = append(ids, res.GetBlog().GetId())
+	}
+	for _, id := range ids[1:] {
+		if _, err := s.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: id}); err != nil {
+			t.Fatalf("DeleteBlog(%v) had unexpected error: %v", id, err)
+		}
+	}
+
+	if _, err := s.PurgeBlog(ctx, &blogpb.PurgeBlogRequest{BlogId: ids[1]}); err != nil {
+		t.Fatalf("PurgeBlog() had unexpected error: %v", err)
+	}
+	_, err := s.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: ids[1], ShowDeleted: true})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("ReadBlog(purged, show_deleted) got code %v, want %v", code, codes.NotFound)
+	}
+
+	// nothing has been in the trash for an hour yet
+	if purged, err := s.purgeTrash(ctx, time.Hour); err != nil || purged != 0 {
+		t.Errorf("purgeTrash(1h) got %d, %v, want 0, nil", purged, err)
+	}
+	if purged, err := s.purgeTrash(ctx, -time.Second); err != nil || purged != 1 {
+		t.Errorf("purgeTrash(-1s) got %d, %v, want 1, nil", purged, err)
+	}
+	if got := listIDs(t, ctx, s, blogpb.ListBlogRequest_WITH_TRASHED); len(got) != 1 || got[0] != ids[0] {
+		t.Errorf("ListBlogsPage(WITH_TRASHED) after purge got %v, want [%v]", got, ids[0])
+	}
+}
+
+func TestServer_TrashVisibility(t *testing.T) {
+	s := newTestServer()
+	anonymous := context.Background()
+	khoi := auth.NewContext(context.Background(), &auth.Identity{Subject: "Khoi"})
+	john := auth.NewContext(context.Background(), &auth.Identity{Subject: "John"})
+
+	var ids []string
+	for _, ctx := range []context.Context{khoi, john} {
+		res, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "trash", Content: "trash"}})
+		if err != nil {
+			t.Fatalf("CreateBlog() had unexpected error: %v", err)
+		}
+		if _, err := s.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: res.GetBlog().GetId()}); err != nil {
+			t.Fatalf("DeleteBlog() had unexpected error: %v", err)
+		}
+		ids = append(ids, res.GetBlog().GetId())
+	}
+	mine := ids[0]
+
+	readTests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"anonymous", anonymous, codes.Unauthenticated},
+		{"other author", john, codes.PermissionDenied},
+		{"author", khoi, codes.OK},
+		{"admin", adminContext(), codes.OK},
+	}
+	for _, tt := range readTests {
+		_, err := s.ReadBlog(tt.ctx, &blogpb.ReadBlogRequest{BlogId: mine, ShowDeleted: true})
+		if code := status.Code(err); code != tt.want {
+			t.Errorf("ReadBlog(show_deleted) by %s got code %v, want %v", tt.name, code, tt.want)
+		}
+	}
+
+	_, err := s.ListBlogsPage(anonymous, &blogpb.ListBlogRequest{Trash: blogpb.ListBlogRequest_ONLY_TRASHED})
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Errorf("ListBlogsPage(ONLY_TRASHED) by anonymous got code %v, want %v", code, codes.Unauthenticated)
+	}
+	_, err = s.ListBlogsPage(khoi, &blogpb.ListBlogRequest{AuthorId: "John", Trash: blogpb.ListBlogRequest_WITH_TRASHED})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("ListBlogsPage(WITH_TRASHED) of another author got code %v, want %v", code, codes.PermissionDenied)
+	}
+	if got := listIDs(t, khoi, s, blogpb.ListBlogRequest_ONLY_TRASHED); len(got) != 1 || got[0] != mine {
+		t.Errorf("ListBlogsPage(ONLY_TRASHED) by the author got %v, want [%v]", got, mine)
+	}
+	if got := listIDs(t, adminContext(), s, blogpb.ListBlogRequest_ONLY_TRASHED); len(got) != 2 {
+		t.Errorf("ListBlogsPage(ONLY_TRASHED) by an admin got %v, want both blogs", got)
+	}
+
+	getReq := &blogpb.BatchGetBlogsRequest{BlogIds: ids, ShowDeleted: true}
+	getRes, err := s.BatchGetBlogs(khoi, getReq)
+	if err != nil {
+		t.Fatalf("BatchGetBlogs(show_deleted) had unexpected error: %v", err)
+	}
+	want := []codes.Code{codes.OK, codes.NotFound}
+	if got := resultCodes(getRes.GetResults()); !equalCodes(got, want) {
+		t.Errorf("BatchGetBlogs(show_deleted) by the author of the first got codes %v, want %v", got, want)
+	}
+
+	_, err = s.ListBlogRevisions(john, &blogpb.ListBlogRevisionsRequest{BlogId: mine})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("ListBlogRevisions(trashed) by another author got code %v, want %v", code, codes.NotFound)
+	}
+	_, err = s.GetBlogRevision(anonymous, &blogpb.GetBlogRevisionRequest{BlogId: mine, Revision: 2})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("GetBlogRevision(trashed) by anonymous got code %v, want %v", code, codes.NotFound)
+	}
+	if _, err := s.ListBlogRevisions(khoi, &blogpb.ListBlogRevisionsRequest{BlogId: mine}); err != nil {
+		t.Errorf("ListBlogRevisions(trashed) by the author had unexpected error: %v", err)
+	}
+	if _, err := s.GetBlogRevision(khoi, &blogpb.GetBlogRevisionRequest{BlogId: mine, Revision: 2}); err != nil {
+		t.Errorf("GetBlogRevision(trashed) by the author had unexpected error: %v", err)
+	}
+}
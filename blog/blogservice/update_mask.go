@@ -0,0 +1,73 @@
+package blogservice
+
+import (
+	"fmt"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/validate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// updatablePaths lists the Blog fields a client may change, in the order they
+// are applied when the update mask is empty.
+var updatablePaths = []string{"author_id", "title", "content", "tags"}
+
+// updatePaths returns the paths an update with the given mask changes. An
+// empty mask updates every updatable field, as UpdateBlog did before masks
+// were supported.
+func updatePaths(mask *fieldmaskpb.FieldMask) []string {
+	if len(mask.GetPaths()) == 0 {
+		return updatablePaths
+	}
+	return mask.GetPaths()
+}
+
+// masks reports whether mask names path explicitly.
+func masks(mask *fieldmaskpb.FieldMask, path string) bool {
+	for _, p := range mask.GetPaths() {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredPaths are the updatable fields a blog cannot be without, which
+// CreateBlog requires, see newBlogFields.
+var requiredPaths = map[string]bool{"title": true, "content": true}
+
+// requiredUpdates checks that an UpdateBlog request sets the required fields
+// it changes, so that no update blanks them.
+func requiredUpdates(req *blogpb.UpdateBlogRequest) error {
+	fields := validate.Fields{}
+	for _, path := range updatePaths(req.GetUpdateMask()) {
+		if requiredPaths[path] {
+			fields["blog."+path] = []validate.Rule{validate.Required()}
+		}
+	}
+	return fields.Validate(req)
+}
+
+// updateSet returns the BlogItem fields, keyed by their bson names, that an
+// UpdateBlog request with the given mask changes, see updatePaths.
+func updateSet(blog *blogpb.Blog, mask *fieldmaskpb.FieldMask) (bson.M, error) {
+	set := bson.M{}
+	for _, path := range updatePaths(mask) {
+		switch path {
+		case "author_id":
+			set["author_id"] = blog.GetAuthorId()
+		case "title":
+			set["title"] = blog.GetTitle()
+		case "content":
+			set["content"] = blog.GetContent()
+		case "tags":
+			set["tags"] = normalizeTags(blog.GetTags())
+		default:
+			return nil, fmt.Errorf("update_mask path %q is not one of %v", path, updatablePaths)
+		}
+	}
+
+	return set, nil
+}
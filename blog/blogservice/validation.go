@@ -0,0 +1,130 @@
+package blogservice
+
+import (
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/validate"
+)
+
+const (
+	maxAuthorIDLength = 100
+	maxTitleLength    = 200
+	maxContentLength  = 100000
+	maxTags           = 20
+	maxTagLength      = 50
+	maxQueryLength    = 200
+	maxTokenLength    = 1000
+	maxCommentLength  = 10000
+)
+
+var (
+	// idRules are the rules for the ID of the blog or comment a request is about.
+	idRules = []validate.Rule{validate.Required(), validate.ObjectID()}
+
+	// blogFields are the rules for the fields of a Blog sent by a client.
+	blogFields = validate.Fields{
+		"author_id": {validate.MaxLen(maxAuthorIDLength)},
+		"title":     {validate.MaxLen(maxTitleLength)},
+		"content":   {validate.MaxLen(maxContentLength)},
+		"tags":      {validate.MaxItems(maxTags), validate.Each(validate.MaxLen(maxTagLength))},
+	}
+
+	// newBlogFields are the rules for a Blog to create, which are checked per
+	// item in batches.
+	newBlogFields = blogFields.With(validate.Fields{
+		"title":   {validate.Required()},
+		"content": {validate.Required()},
+	})
+)
+
+// RequestRules are the rules the requests of the BlogService and the
+// CommentService must follow, checked by the validate interceptors.
+var RequestRules = validate.Rules{}.
+	For(&blogpb.CreateBlogRequest{}, validate.Fields{
+		"blog": {validate.Required()},
+	}.With(newBlogFields.Prefix("blog"))).
+	For(&blogpb.ReadBlogRequest{}, validate.Fields{
+		"blog_id":       idRules,
+		"render_format": {validate.DefinedEnum()},
+	}).
+	For(&blogpb.UpdateBlogRequest{}, validate.Fields{
+		"blog":              {validate.Required()},
+		"blog.id":           idRules,
+		"expected_revision": {validate.Min(0)},
+	}.With(blogFields.Prefix("blog"))).
+	For(&blogpb.DeleteBlogRequest{}, validate.Fields{
+		"blog_id":           idRules,
+		"expected_revision": {validate.Min(0)},
+	}).
+	For(&blogpb.ListBlogRequest{}, validate.Fields{
+		"page_size":     {validate.Min(0)},
+		"author_id":     {validate.MaxLen(maxAuthorIDLength)},
+		"sort_order":    {validate.DefinedEnum()},
+		"trash":         {validate.DefinedEnum()},
+		"tags":          {validate.MaxItems(maxTags), validate.Each(validate.MaxLen(maxTagLength))},
+		"render_format": {validate.DefinedEnum()},
+	}).
+	For(&blogpb.SearchBlogsRequest{}, validate.Fields{
+		"query": {validate.Required(), validate.MaxLen(maxQueryLength)},
+		"limit": {validate.Min(0)},
+	}).
+	For(&blogpb.WatchBlogsRequest{}, validate.Fields{
+		"resume_token": {validate.MaxLen(maxTokenLength)},
+	}).
+	For(&blogpb.RestoreBlogRequest{}, validate.Fields{
+		"blog_id": idRules,
+	}).
+	For(&blogpb.PurgeBlogRequest{}, validate.Fields{
+		"blog_id": idRules,
+	}).
+	For(&blogpb.BatchCreateBlogsRequest{}, validate.Fields{
+		"blogs": {validate.MaxItems(maxBatchSize)},
+	}).
+	For(&blogpb.BatchGetBlogsRequest{}, validate.Fields{
+		"blog_ids": {validate.MaxItems(maxBatchSize)},
+	}).
+	For(&blogpb.BatchDeleteBlogsRequest{}, validate.Fields{
+		"blog_ids": {validate.MaxItems(maxBatchSize)},
+	}).
+	For(&blogpb.ListBlogRevisionsRequest{}, validate.Fields{
+		"blog_id": idRules,
+	}).
+	For(&blogpb.GetBlogRevisionRequest{}, validate.Fields{
+		"blog_id":             idRules,
+		"revision":            {validate.Min(1)},
+		"compare_to_revision": {validate.Min(0)},
+	}).
+	For(&blogpb.RollbackBlogRequest{}, validate.Fields{
+		"blog_id":           idRules,
+		"revision":          {validate.Min(1)},
+		"expected_revision": {validate.Min(0)},
+	}).
+	For(&blogpb.ListTagsRequest{}, validate.Fields{
+		"limit": {validate.Min(0)},
+	}).
+	For(&blogpb.ExportBlogsRequest{}, validate.Fields{
+		"trash":     {validate.DefinedEnum()},
+		"author_id": {validate.MaxLen(maxAuthorIDLength)},
+	}).
+	For(&blogpb.ImportBlogsRequest{}, validate.Fields{
+		"blog":          {validate.Required()},
+		"blog.id":       idRules,
+		"blog.revision": {validate.Min(0)},
+	}.With(newBlogFields.Prefix("blog"))).
+	For(&blogpb.AddCommentRequest{}, validate.Fields{
+		"comment":           {validate.Required()},
+		"comment.blog_id":   idRules,
+		"comment.parent_id": {validate.ObjectID()},
+		"comment.content":   {validate.Required(), validate.MaxLen(maxCommentLength)},
+	}).
+	For(&blogpb.ListCommentsRequest{}, validate.Fields{
+		"blog_id":   idRules,
+		"parent_id": {validate.ObjectID()},
+		"page_size": {validate.Min(0)},
+	}).
+	For(&blogpb.EditCommentRequest{}, validate.Fields{
+		"comment_id": idRules,
+		"content":    {validate.Required(), validate.MaxLen(maxCommentLength)},
+	}).
+	For(&blogpb.DeleteCommentRequest{}, validate.Fields{
+		"comment_id": idRules,
+	})
@@ -0,0 +1,52 @@
+package blogservice
+
+import (
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func TestRequestRules_Paths(t *testing.T) {
+	// Validate panics on a path that is not a field of the request
+	for name := range RequestRules {
+		mt, err := protoregistry.GlobalTypes.FindMessageByName(name)
+		if err != nil {
+			t.Fatalf("FindMessageByName(%v) had unexpected error: %v", name, err)
+		}
+		RequestRules.Validate(mt.New().Interface())
+	}
+}
+
+func TestRequestRules(t *testing.T) {
+	tests := []struct {
+		req  proto.Message
+		want codes.Code
+	}{
+		{&blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "t", Content: "c"}}, codes.OK},
+		{&blogpb.CreateBlogRequest{}, codes.InvalidArgument},
+		{&blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Content: "c"}}, codes.InvalidArgument},
+		{&blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "t", Content: "c", Tags: make([]string, maxTags+1)}}, codes.InvalidArgument},
+		{&blogpb.ReadBlogRequest{BlogId: "5bdc29e661b75adcac496cf4"}, codes.OK},
+		{&blogpb.ReadBlogRequest{BlogId: "not-an-id"}, codes.InvalidArgument},
+		{&blogpb.ReadBlogRequest{BlogId: "5bdc29e661b75adcac496cf4", RenderFormat: 7}, codes.InvalidArgument},
+		{&blogpb.ListBlogRequest{RenderFormat: blogpb.RenderFormat_HTML}, codes.OK},
+		{&blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: "5bdc29e661b75adcac496cf4"}}, codes.OK},
+		{&blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Title: "no id"}}, codes.InvalidArgument},
+		{&blogpb.DeleteBlogRequest{}, codes.InvalidArgument},
+		{&blogpb.SearchBlogsRequest{Query: "grpc", Limit: -1}, codes.InvalidArgument},
+		{&blogpb.ImportBlogsRequest{Blog: &blogpb.Blog{Id: "5bdc29e661b75adcac496cf4", Title: "t", Content: "c"}}, codes.OK},
+		{&blogpb.ImportBlogsRequest{Blog: &blogpb.Blog{Title: "t", Content: "c"}}, codes.InvalidArgument},
+		{&blogpb.EditCommentRequest{CommentId: "5bdc29e661b75adcac496cf4"}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		if got := status.Code(RequestRules.Validate(tt.req)); got != tt.want {
+			t.Errorf("Validate(%v) got code %v, want %v", tt.req, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package blogservice
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// feedHistorySize is how many past events a watcher can resume from.
+	feedHistorySize = 1024
+
+	// watcherBuffer is how many events may queue up for a slow watcher before
+	// it is disconnected and has to resume.
+	watcherBuffer = 64
+)
+
+var (
+	errBadResumeToken     = errors.New("resume_token is malformed")
+	errResumeTokenExpired = errors.New("resume_token is too old or from a previous server run, list the blogs again and watch without a token")
+)
+
+// changeFeed fans out the blog changes made through this server to the
+// WatchBlogs streams. Changes made by other server processes sharing the same
+// database are not seen.
+type changeFeed struct {
+	mu sync.Mutex
+
+	// epoch identifies this feed so resume tokens from a previous run are rejected.
+	epoch   string
+	lastSeq uint64
+	history []*blogpb.WatchBlogsResponse // the most recent events, oldest first
+	subs    map[*watcher]struct{}
+}
+
+// watcher is a subscription to a changeFeed. Its events channel is closed if
+// the watcher falls behind.
+type watcher struct {
+	events chan *blogpb.WatchBlogsResponse
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{
+		epoch: primitive.NewObjectID().Hex(),
+		subs:  make(map[*watcher]struct{}),
+	}
+}
+
+// publish records a change and delivers it to every watcher. Watchers may be
+// anonymous while trashed blogs are only shown to their author and admins, so
+// the events of a blog in or out of the trash only carry its ID and revision.
+func (f *changeFeed) publish(eventType blogpb.WatchBlogsResponse_EventType, blog *blogpb.Blog) {
+	switch {
+	case eventType == blogpb.WatchBlogsResponse_DELETED,
+		eventType == blogpb.WatchBlogsResponse_PURGED,
+		blog.GetDeletedAt() != nil:
+		blog = &blogpb.Blog{Id: blog.GetId(), Revision: blog.GetRevision()}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastSeq++
+	event := &blogpb.WatchBlogsResponse{
+		Type:        eventType,
+		Blog:        blog,
+		ResumeToken: fmt.Sprintf("%s-%d", f.epoch, f.lastSeq),
+		EventTime:   timestamppb.Now(),
+	}
+
+	f.history = append(f.history, event)
+	if len(f.history) > feedHistorySize {
+		f.history = f.history[len(f.history)-feedHistorySize:]
+	}
+
+	for w := range f.subs {
+		select {
+		case w.events <- event:
+		default:
+			// the watcher is too slow, let it resume from its last token
+			close(w.events)
+			delete(f.subs, w)
+		}
+	}
+}
+
+// subscribe registers a new watcher. If resumeToken is set, the events after
+// it that are still in the history are returned to be sent first.
+func (f *changeFeed) subscribe(resumeToken string) (*watcher, []*blogpb.WatchBlogsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var backlog []*blogpb.WatchBlogsResponse
+	if resumeToken != "" {
+		seq, err := f.parseToken(resumeToken)
+		if err != nil {
+			return nil, nil, err
+		}
+		oldest := f.lastSeq - uint64(len(f.history)) + 1
+		if seq+1 < oldest {
+			return nil, nil, errResumeTokenExpired
+		}
+		backlog = append(backlog, f.history[seq+1-oldest:]...)
+	}
+
+	w := &watcher{events: make(chan *blogpb.WatchBlogsResponse, watcherBuffer)}
+	f.subs[w] = struct{}{}
+
+	return w, backlog, nil
+}
+
+func (f *changeFeed) unsubscribe(w *watcher) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.subs, w)
+}
+
+// parseToken returns the sequence number of a resume token. f.mu must be held.
+func (f *changeFeed) parseToken(token string) (uint64, error) {
+	i := strings.LastIndex(token, "-")
+	if i < 0 {
+		return 0, errBadResumeToken
+	}
+	seq, err := strconv.ParseUint(token[i+1:], 10, 64)
+	if err != nil {
+		return 0, errBadResumeToken
+	}
+	if token[:i] != f.epoch {
+		return 0, errResumeTokenExpired
+	}
+	if seq > f.lastSeq {
+		return 0, errBadResumeToken
+	}
+
+	return seq, nil
+}
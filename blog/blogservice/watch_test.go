@@ -0,0 +1,131 @@
+package blogservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchStream collects the events sent on a WatchBlogs stream and ends the
+// call once it has received want events.
+type watchStream struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	want   int
+	events []*blogpb.WatchBlogsResponse
+}
+
+func newWatchStream(want int) *watchStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &watchStream{ctx: ctx, cancel: cancel, want: want}
+}
+
+func (s *watchStream) Context() context.Context { return s.ctx }
+
+func (s *watchStream) Send(event *blogpb.WatchBlogsResponse) error {
+	s.events = append(s.events, event)
+	if len(s.events) == s.want {
+		s.cancel()
+	}
+	return nil
+}
+
+func TestServer_WatchBlogs(t *testing.T) {
+	ctx := adminContext()
+	s := newTestServer()
+
+	// subscribe before any change is made
+	w, _, err := s.feed.subscribe("")
+	if err != nil {
+		t.Fatalf("subscribe() had unexpected error: %v", err)
+	}
+	defer s.feed.unsubscribe(w)
+
+	createRes, err := s.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "v1", Content: "c"}})
+	if err != nil {
+		t.Fatalf("CreateBlog() had unexpected error: %v", err)
+	}
+	blog := createRes.GetBlog()
+	blog.Title = "v2"
+	if _, err := s.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: blog}); err != nil {
+		t.Fatalf("UpdateBlog() had unexpected error: %v", err)
+	}
+	if _, err := s.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: blog.GetId()}); err != nil {
+		t.Fatalf("DeleteBlog() had unexpected error: %v", err)
+	}
+
+	wantTypes := []blogpb.WatchBlogsResponse_EventType{
+		blogpb.WatchBlogsResponse_CREATED,
+		blogpb.WatchBlogsResponse_UPDATED,
+		blogpb.WatchBlogsResponse_DELETED,
+	}
+	var events []*blogpb.WatchBlogsResponse
+	for i, want := range wantTypes {
+		event := <-w.events
+		if event.GetType() != want || event.GetBlog().GetId() != blog.GetId() {
+			t.Errorf("event %d got %v for blog %v, want %v for blog %v", i, event.GetType(), event.GetBlog().GetId(), want, blog.GetId())
+		}
+		events = append(events, event)
+	}
+	first := events[0]
+	if got := first.GetBlog().GetContent(); got != "c" {
+		t.Errorf("CREATED event got content %q, want %q", got, "c")
+	}
+	// anyone can watch, so the trashed blog is only identified
+	if deleted := events[2].GetBlog(); deleted.GetTitle() != "" || deleted.GetContent() != "" || deleted.GetRevision() != 3 {
+		t.Errorf("DELETED event got blog %v, want only its id and revision 3", deleted)
+	}
+
+	// a client that only saw the first event resumes with the other two
+	stream := newWatchStream(2)
+	if err := s.WatchBlogs(&blogpb.WatchBlogsRequest{ResumeToken: first.GetResumeToken()}, stream); err != nil {
+		t.Fatalf("WatchBlogs(resume) had unexpected error: %v", err)
+	}
+	if len(stream.events) != 2 || stream.events[0].GetType() != blogpb.WatchBlogsResponse_UPDATED || stream.events[1].GetType() != blogpb.WatchBlogsResponse_DELETED {
+		t.Errorf("WatchBlogs(resume) got %v, want the UPDATED and DELETED events", stream.events)
+	}
+}
+
+func TestServer_WatchBlogsBadToken(t *testing.T) {
+	s := newTestServer()
+
+	tests := []struct {
+		token string
+		want  codes.Code
+	}{
+		{"garbage", codes.InvalidArgument},
+		{s.feed.epoch + "-5", codes.InvalidArgument},
+		{"5bdc29e661b75adcac496cf4-0", codes.OutOfRange},
+	}
+	for _, tt := range tests {
+		err := s.WatchBlogs(&blogpb.WatchBlogsRequest{ResumeToken: tt.token}, newWatchStream(1))
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("WatchBlogs(%q) got code %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestChangeFeed_ExpiredToken(t *testing.T) {
+	f := newChangeFeed()
+	for i := 0; i < feedHistorySize+2; i++ {
+		f.publish(blogpb.WatchBlogsResponse_CREATED, &blogpb.Blog{})
+	}
+
+	if _, _, err := f.subscribe(f.epoch + "-1"); err != errResumeTokenExpired {
+		t.Errorf("subscribe(evicted token) got error %v, want %v", err, errResumeTokenExpired)
+	}
+	_, backlog, err := f.subscribe(f.epoch + "-2")
+	if err != nil {
+		t.Fatalf("subscribe(oldest token) had unexpected error: %v", err)
+	}
+	if len(backlog) != feedHistorySize {
+		t.Errorf("subscribe(oldest token) got %d events, want %d", len(backlog), feedHistorySize)
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// BlogRevision is a past version of a blog, archived when it was replaced.
+type BlogRevision struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	BlogID   primitive.ObjectID `bson:"blog_id"`
+	Revision int64              `bson:"revision"`
+	Blog     BlogItem           `bson:"blog"`
+}
@@ -0,0 +1,110 @@
+// Package render converts the Markdown content of blogs to HTML or plain text
+// and computes the excerpt and reading statistics shown with a blog.
+package render
+
+import (
+	"bytes"
+	"math"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+const (
+	// ExcerptWords is how many words of the plain text an excerpt keeps.
+	ExcerptWords = 50
+
+	// WordsPerMinute is the reading speed reading times are based on.
+	WordsPerMinute = 200
+)
+
+// markdown renders GitHub flavored Markdown. It is left in goldmark's safe
+// mode, which drops raw HTML and links with dangerous schemes such as
+// javascript:, so the HTML it produces can be embedded in a page as is.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// HTML converts Markdown to sanitised HTML.
+func HTML(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// PlainText strips the Markdown syntax from src, keeping one line per block
+// and leaving out raw HTML.
+func PlainText(src string) string {
+	source := []byte(src)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	var b strings.Builder
+	endLine := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *ast.RawHTML, *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering {
+				b.Write(n.Segment.Value(source))
+				switch {
+				case n.HardLineBreak():
+					b.WriteByte('\n')
+				case n.SoftLineBreak():
+					b.WriteByte(' ')
+				}
+			}
+		case *ast.String:
+			if entering {
+				b.Write(n.Value)
+			}
+		case *ast.AutoLink:
+			if entering {
+				b.Write(n.Label(source))
+			}
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			if entering {
+				lines := n.Lines()
+				for i := 0; i < lines.Len(); i++ {
+					segment := lines.At(i)
+					b.Write(segment.Value(source))
+				}
+			}
+		}
+		if !entering && n.Type() == ast.TypeBlock {
+			endLine()
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return strings.TrimSpace(b.String())
+}
+
+// Excerpt returns the first ExcerptWords words of text, followed by "…" if
+// there are more.
+func Excerpt(text string) string {
+	words := strings.Fields(text)
+	if len(words) <= ExcerptWords {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:ExcerptWords], " ") + "…"
+}
+
+// WordCount returns the number of words in text.
+func WordCount(text string) int {
+	return len(strings.Fields(text))
+}
+
+// ReadingTime returns how many minutes it takes to read words words, rounded
+// up so any text takes at least a minute.
+func ReadingTime(words int) int {
+	return int(math.Ceil(float64(words) / WordsPerMinute))
+}
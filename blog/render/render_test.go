@@ -0,0 +1,82 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+const post = `# Hello *gRPC*
+
+Some **bold** text and a [link](https://example.com),
+continued on the next line.
+
+<script>alert("x")</script>
+
+- one
+- two
+
+` + "```go\nfmt.Println(\"hi\")\n```\n"
+
+func TestHTML(t *testing.T) {
+	got, err := HTML(post)
+	if err != nil {
+		t.Fatalf("HTML() had unexpected error: %v", err)
+	}
+	for _, want := range []string{"<h1>Hello <em>gRPC</em></h1>", "<strong>bold</strong>", `<a href="https://example.com">link</a>`, "<li>one</li>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML() got %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "<script>") {
+		t.Errorf("HTML() got %q, want raw HTML dropped", got)
+	}
+
+	got, err = HTML(`[click](javascript:alert(1))`)
+	if err != nil {
+		t.Fatalf("HTML() had unexpected error: %v", err)
+	}
+	if strings.Contains(got, "javascript:") {
+		t.Errorf("HTML() got %q, want the javascript: link dropped", got)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	want := strings.Join([]string{
+		"Hello gRPC",
+		"Some bold text and a link, continued on the next line.",
+		"one",
+		"two",
+		`fmt.Println("hi")`,
+	}, "\n")
+	if got := PlainText(post); got != want {
+		t.Errorf("PlainText() got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	if got := Excerpt("a  short\ntext"); got != "a short text" {
+		t.Errorf("Excerpt(short) got %q, want %q", got, "a short text")
+	}
+
+	long := strings.Repeat("word ", ExcerptWords+1)
+	got := Excerpt(long)
+	if !strings.HasSuffix(got, "…") || WordCount(got) != ExcerptWords {
+		t.Errorf("Excerpt(long) got %q, want %d words and …", got, ExcerptWords)
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	tests := []struct {
+		words, want int
+	}{
+		{0, 0},
+		{1, 1},
+		{WordsPerMinute, 1},
+		{WordsPerMinute + 1, 2},
+	}
+	for _, tt := range tests {
+		if got := ReadingTime(tt.words); got != tt.want {
+			t.Errorf("ReadingTime(%d) got %d, want %d", tt.words, got, tt.want)
+		}
+	}
+}
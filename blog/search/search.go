@@ -0,0 +1,206 @@
+// Package search implements the tokenizer, inverted index and snippet
+// highlighting behind BlogService.SearchBlogs.
+package search
+
+import (
+	"bytes"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TitleWeight is how much more a term in the title counts than one in the
+// content. The MongoDB text index is created with the same weights.
+const TitleWeight = 2
+
+// token is a word of a text together with its byte offsets.
+type token struct {
+	term       string
+	start, end int
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// Terms returns the distinct lower-cased words of text in order of appearance.
+func Terms(text string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, t := range tokenize(text) {
+		if !seen[t.term] {
+			seen[t.term] = true
+			terms = append(terms, t.term)
+		}
+	}
+	return terms
+}
+
+// Hit is a document matching a query, with its relevance score.
+type Hit struct {
+	ID    primitive.ObjectID
+	Score float64
+}
+
+// Index is an inverted index over blog titles and contents.
+// It is not safe for concurrent use.
+type Index struct {
+	// postings maps a term to the weighted term frequency in each document.
+	postings map[string]map[primitive.ObjectID]float64
+	// docs maps a document to its terms so it can be removed.
+	docs map[primitive.ObjectID][]string
+}
+
+// NewIndex returns an empty Index.
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[primitive.ObjectID]float64),
+		docs:     make(map[primitive.ObjectID][]string),
+	}
+}
+
+// Add indexes a document, replacing any previous version with the same ID.
+func (idx *Index) Add(id primitive.ObjectID, title, content string) {
+	idx.Remove(id)
+
+	freq := map[string]float64{}
+	for _, t := range tokenize(title) {
+		freq[t.term] += TitleWeight
+	}
+	for _, t := range tokenize(content) {
+		freq[t.term]++
+	}
+
+	terms := make([]string, 0, len(freq))
+	for term, f := range freq {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[primitive.ObjectID]float64)
+		}
+		idx.postings[term][id] = f
+		terms = append(terms, term)
+	}
+	idx.docs[id] = terms
+}
+
+// Remove drops a document from the index.
+func (idx *Index) Remove(id primitive.ObjectID) {
+	for _, term := range idx.docs[id] {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// Search returns up to limit documents containing any word of query, best
+// first. Each query term contributes its weighted frequency in the document
+// scaled by how rare the term is across the index (tf-idf).
+func (idx *Index) Search(query string, limit int) []Hit {
+	scores := map[primitive.ObjectID]float64{}
+	n := float64(len(idx.docs))
+	for _, term := range Terms(query) {
+		docs := idx.postings[term]
+		idf := math.Log(1 + n/float64(len(docs)))
+		for id, f := range docs {
+			scores[id] += f * idf
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return bytes.Compare(hits[i].ID[:], hits[j].ID[:]) < 0
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
+
+// snippetContext is the number of words kept on each side of a match.
+const snippetContext = 6
+
+// Snippets returns up to max excerpts of text around the words of query.
+// The excerpts are HTML-escaped and every matching word is wrapped in <em>.
+func Snippets(text, query string, max int) []string {
+	want := map[string]bool{}
+	for _, term := range Terms(query) {
+		want[term] = true
+	}
+
+	tokens := tokenize(text)
+	var snippets []string
+	for i := 0; i < len(tokens) && len(snippets) < max; i++ {
+		if !want[tokens[i].term] {
+			continue
+		}
+
+		// grow the window while further matches fall into it
+		first := i - snippetContext
+		if first < 0 {
+			first = 0
+		}
+		last := i + snippetContext
+		for j := i + 1; j < len(tokens) && j <= last; j++ {
+			if want[tokens[j].term] {
+				last = j + snippetContext
+			}
+		}
+		if last >= len(tokens) {
+			last = len(tokens) - 1
+		}
+
+		snippets = append(snippets, highlight(text, tokens, first, last, want))
+		i = last
+	}
+
+	return snippets
+}
+
+// highlight renders tokens[first..last] of text as an escaped excerpt.
+func highlight(text string, tokens []token, first, last int, want map[string]bool) string {
+	var b strings.Builder
+	if first > 0 {
+		b.WriteString("…")
+	}
+	pos := tokens[first].start
+	for _, t := range tokens[first : last+1] {
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		if want[t.term] {
+			b.WriteString("<em>" + html.EscapeString(text[t.start:t.end]) + "</em>")
+		} else {
+			b.WriteString(html.EscapeString(text[t.start:t.end]))
+		}
+		pos = t.end
+	}
+	if last < len(tokens)-1 {
+		b.WriteString("…")
+	}
+	return b.String()
+}
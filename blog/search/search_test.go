@@ -0,0 +1,54 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTerms(t *testing.T) {
+	got := Terms("Hello, gRPC world! hello again")
+	want := []string{"hello", "grpc", "world", "again"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Terms() got %q, want %q", got, want)
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx := NewIndex()
+	title, content, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	idx.Add(title, "Learning gRPC", "A post about Go")
+	idx.Add(content, "Notes", "Streaming RPCs in gRPC are neat")
+	idx.Add(other, "Cooking", "Pasta recipes")
+
+	hits := idx.Search("grpc", 10)
+	if len(hits) != 2 {
+		t.Fatalf("Search(grpc) got %d hits, want 2", len(hits))
+	}
+	if hits[0].ID != title || hits[1].ID != content {
+		t.Errorf("Search(grpc) got order %v, %v, want the title match first", hits[0].ID.Hex(), hits[1].ID.Hex())
+	}
+
+	idx.Remove(title)
+	if hits := idx.Search("grpc", 10); len(hits) != 1 || hits[0].ID != content {
+		t.Errorf("Search(grpc) after Remove got %v, want only %v", hits, content.Hex())
+	}
+
+	idx.Add(other, "Cooking", "Pasta and gRPC")
+	if hits := idx.Search("pasta recipes", 10); len(hits) != 1 {
+		t.Errorf("Search(pasta recipes) after re-Add got %d hits, want 1", len(hits))
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen gRPC <b>"
+	got := Snippets(text, "three GRPC", 3)
+	want := []string{
+		"one two <em>three</em> four five six seven eight nine…",
+		"…ten eleven twelve thirteen fourteen fifteen <em>gRPC</em> &lt;b",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Snippets() got %q, want %q", got, want)
+	}
+}
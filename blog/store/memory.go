@@ -0,0 +1,454 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/search"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryBlogStore is a BlogStore that keeps blogs in process memory.
+// It is useful for tests and for running the server without MongoDB.
+type MemoryBlogStore struct {
+	mu    sync.RWMutex
+	items map[primitive.ObjectID]models.BlogItem
+	index *search.Index
+}
+
+// NewMemoryBlogStore returns an empty in-memory BlogStore.
+func NewMemoryBlogStore() *MemoryBlogStore {
+	return &MemoryBlogStore{
+		items: make(map[primitive.ObjectID]models.BlogItem),
+		index: search.NewIndex(),
+	}
+}
+
+func (s *MemoryBlogStore) Create(ctx context.Context, item *models.BlogItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item.ID = primitive.NewObjectID()
+	s.put(*item)
+
+	return nil
+}
+
+func (s *MemoryBlogStore) CreateMany(ctx context.Context, items []*models.BlogItem) []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		item.ID = primitive.NewObjectID()
+		s.put(*item)
+	}
+
+	return make([]error, len(items))
+}
+
+func (s *MemoryBlogStore) Get(ctx context.Context, id primitive.ObjectID) (*models.BlogItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &item, nil
+}
+
+func (s *MemoryBlogStore) GetMany(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*models.BlogItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make(map[primitive.ObjectID]*models.BlogItem, len(ids))
+	for _, id := range ids {
+		if item, ok := s.items[id]; ok {
+			found[id] = &item
+		}
+	}
+
+	return found, nil
+}
+
+func (s *MemoryBlogStore) Put(ctx context.Context, item *models.BlogItem) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.items[item.ID]
+	s.put(*item)
+
+	return !exists, nil
+}
+
+func (s *MemoryBlogStore) Update(ctx context.Context, id primitive.ObjectID, set bson.M, expectedRevision int64) (*models.BlogItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.check(id, expectedRevision); err != nil {
+		return nil, err
+	}
+
+	return s.update(id, set)
+}
+
+// update applies set to an existing blog and increments its revision.
+// s.mu must be held.
+func (s *MemoryBlogStore) update(id primitive.ObjectID, set bson.M) (*models.BlogItem, error) {
+	// round-trip through bson so set uses the same field names as in MongoDB
+	doc := bson.M{}
+	if err := remarshal(s.items[id], &doc); err != nil {
+		return nil, err
+	}
+	for k, v := range set {
+		doc[k] = v
+	}
+	item := models.BlogItem{}
+	if err := remarshal(doc, &item); err != nil {
+		return nil, err
+	}
+	item.Revision++
+	s.put(item)
+
+	return &item, nil
+}
+
+func (s *MemoryBlogStore) Delete(ctx context.Context, id primitive.ObjectID, expectedRevision int64) (*models.BlogItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.check(id, expectedRevision); err != nil {
+		return nil, err
+	}
+	item := s.items[id]
+	delete(s.items, id)
+	s.index.Remove(id)
+
+	return &item, nil
+}
+
+// put stores item and indexes it for search unless it is in the trash.
+// s.mu must be held.
+func (s *MemoryBlogStore) put(item models.BlogItem) {
+	s.items[item.ID] = item
+	if item.DeletedAt.IsZero() {
+		s.index.Add(item.ID, item.Title, item.Content)
+	} else {
+		s.index.Remove(item.ID)
+	}
+}
+
+// check verifies that the blog exists at expectedRevision. s.mu must be held.
+func (s *MemoryBlogStore) check(id primitive.ObjectID, expectedRevision int64) error {
+	stored, ok := s.items[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if expectedRevision != AnyRevision && stored.Revision != expectedRevision {
+		return ErrRevisionMismatch
+	}
+
+	return nil
+}
+
+// List visits blogs in ID order, which matches insertion order since ObjectIDs
+// start with a timestamp. fn is called on a snapshot, so it may use the store.
+func (s *MemoryBlogStore) List(ctx context.Context, opts ListOptions, fn func(*models.BlogItem) error) error {
+	items := s.snapshot()
+	if opts.Descending {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	visited := 0
+	for _, item := range items {
+		if opts.Limit > 0 && visited == opts.Limit {
+			break
+		}
+		if opts.AuthorID != "" && item.AuthorID != opts.AuthorID {
+			continue
+		}
+		if !opts.keepTags(item) {
+			continue
+		}
+		if !opts.keepTrash(item) {
+			continue
+		}
+		if !opts.After.IsZero() && !isAfter(item.ID, opts.After, opts.Descending) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+		visited++
+	}
+
+	return nil
+}
+
+// isAfter reports whether id comes after cursor in the listing order.
+func isAfter(id, cursor primitive.ObjectID, descending bool) bool {
+	cmp := bytes.Compare(id[:], cursor[:])
+	if descending {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+func (s *MemoryBlogStore) Search(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var hits []SearchHit
+	for _, hit := range s.index.Search(query, limit) {
+		item := s.items[hit.ID]
+		hits = append(hits, SearchHit{Item: &item, Score: hit.Score})
+	}
+
+	return hits, nil
+}
+
+func (s *MemoryBlogStore) Tags(ctx context.Context, limit int) ([]TagCount, error) {
+	counts := map[string]int{}
+	for _, item := range s.snapshot() {
+		if !item.DeletedAt.IsZero() {
+			continue
+		}
+		for _, tag := range item.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	if limit > 0 && len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags, nil
+}
+
+// snapshot returns copies of all blogs sorted by ID.
+func (s *MemoryBlogStore) snapshot() []*models.BlogItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]*models.BlogItem, 0, len(s.items))
+	for _, item := range s.items {
+		item := item
+		items = append(items, &item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return bytes.Compare(items[i].ID[:], items[j].ID[:]) < 0
+	})
+
+	return items
+}
+
+// remarshal copies in to out by encoding it to bson and decoding it back.
+func remarshal(in interface{}, out interface{}) error {
+	bt, err := bson.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(bt, out)
+}
+
+// MemoryHistoryStore is a HistoryStore that keeps revisions in process memory.
+type MemoryHistoryStore struct {
+	mu        sync.RWMutex
+	revisions map[primitive.ObjectID][]models.BlogRevision
+}
+
+// NewMemoryHistoryStore returns an empty in-memory HistoryStore.
+func NewMemoryHistoryStore() *MemoryHistoryStore {
+	return &MemoryHistoryStore{revisions: make(map[primitive.ObjectID][]models.BlogRevision)}
+}
+
+func (s *MemoryHistoryStore) Append(ctx context.Context, item *models.BlogItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rev := newRevision(item)
+	rev.ID = primitive.NewObjectID()
+	s.revisions[item.ID] = append(s.revisions[item.ID], *rev)
+
+	return nil
+}
+
+func (s *MemoryHistoryStore) List(ctx context.Context, blogID primitive.ObjectID) ([]*models.BlogRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var revisions []*models.BlogRevision
+	for _, rev := range s.revisions[blogID] {
+		rev := rev
+		revisions = append(revisions, &rev)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+
+	return revisions, nil
+}
+
+func (s *MemoryHistoryStore) Get(ctx context.Context, blogID primitive.ObjectID, revision int64) (*models.BlogRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, rev := range s.revisions[blogID] {
+		if rev.Revision == revision {
+			return &rev, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *MemoryHistoryStore) DeleteAll(ctx context.Context, blogID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.revisions, blogID)
+
+	return nil
+}
+
+// MemoryCommentStore is a CommentStore that keeps comments in process memory.
+type MemoryCommentStore struct {
+	mu       sync.RWMutex
+	comments map[primitive.ObjectID]models.Comment
+}
+
+// NewMemoryCommentStore returns an empty in-memory CommentStore.
+func NewMemoryCommentStore() *MemoryCommentStore {
+	return &MemoryCommentStore{comments: make(map[primitive.ObjectID]models.Comment)}
+}
+
+func (s *MemoryCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment.ID = primitive.NewObjectID()
+	s.comments[comment.ID] = *comment
+
+	return nil
+}
+
+func (s *MemoryCommentStore) Get(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &comment, nil
+}
+
+func (s *MemoryCommentStore) Update(ctx context.Context, id primitive.ObjectID, set bson.M) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, ok := s.comments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	// round-trip through bson so set uses the same field names as in MongoDB
+	doc := bson.M{}
+	if err := remarshal(comment, &doc); err != nil {
+		return nil, err
+	}
+	for k, v := range set {
+		doc[k] = v
+	}
+	updated := models.Comment{}
+	if err := remarshal(doc, &updated); err != nil {
+		return nil, err
+	}
+	s.comments[id] = updated
+
+	return &updated, nil
+}
+
+func (s *MemoryCommentStore) List(ctx context.Context, opts CommentListOptions, fn func(*models.Comment) error) error {
+	s.mu.RLock()
+	var comments []*models.Comment
+	for _, comment := range s.comments {
+		comment := comment
+		if comment.BlogID != opts.BlogID {
+			continue
+		}
+		if !opts.ParentID.IsZero() && comment.ParentID != opts.ParentID {
+			continue
+		}
+		if !opts.After.IsZero() && !isAfter(comment.ID, opts.After, false) {
+			continue
+		}
+		comments = append(comments, &comment)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(comments, func(i, j int) bool {
+		return bytes.Compare(comments[i].ID[:], comments[j].ID[:]) < 0
+	})
+	if opts.Limit > 0 && len(comments) > opts.Limit {
+		comments = comments[:opts.Limit]
+	}
+
+	// fn runs without the lock held, so it may call back into the store
+	for _, comment := range comments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(comment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryCommentStore) DeleteMany(ctx context.Context, ids []primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.comments, id)
+	}
+
+	return nil
+}
+
+func (s *MemoryCommentStore) DeleteByBlog(ctx context.Context, blogID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, comment := range s.comments {
+		if comment.BlogID == blogID {
+			delete(s.comments, id)
+		}
+	}
+
+	return nil
+}
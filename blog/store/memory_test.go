@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryBlogStore_CRUD(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryBlogStore()
+
+	item := &models.BlogItem{AuthorID: "Khoi", Title: "My first blog", Content: "Content", Tags: []string{"go"}}
+	if err := s.Create(ctx, item); err != nil {
+		t.Fatalf("Create() had unexpected error: %v", err)
+	}
+	if item.ID.IsZero() {
+		t.Fatalf("Create() did not assign an ID")
+	}
+
+	got, err := s.Get(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("Get(%v) had unexpected error: %v", item.ID.Hex(), err)
+	}
+	if !reflect.DeepEqual(got, item) {
+		t.Errorf("Get(%v) got %+v, want %+v", item.ID.Hex(), got, item)
+	}
+
+	// mutating the returned item must not change the stored one
+	got.Title = "changed"
+	if again, _ := s.Get(ctx, item.ID); again.Title != item.Title {
+		t.Errorf("Get(%v) got title %q after caller mutation, want %q", item.ID.Hex(), again.Title, item.Title)
+	}
+
+	updated, err := s.Update(ctx, item.ID, bson.M{"title": "My first blog (edited)"}, AnyRevision)
+	if err != nil {
+		t.Fatalf("Update() had unexpected error: %v", err)
+	}
+	if got, _ := s.Get(ctx, item.ID); !reflect.DeepEqual(got, updated) || got.Title != "My first blog (edited)" || got.Revision != item.Revision+1 {
+		t.Errorf("Get(%v) after Update got %+v, want the edited title at the next revision", item.ID.Hex(), got)
+	}
+
+	if _, err := s.Delete(ctx, item.ID, AnyRevision); err != nil {
+		t.Fatalf("Delete() had unexpected error: %v", err)
+	}
+	if _, err := s.Get(ctx, item.ID); err != ErrNotFound {
+		t.Errorf("Get(%v) after Delete got error %v, want %v", item.ID.Hex(), err, ErrNotFound)
+	}
+}
+
+func TestMemoryBlogStore_NotFound(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryBlogStore()
+	missing := primitive.NewObjectID()
+
+	if _, err := s.Get(ctx, missing); err != ErrNotFound {
+		t.Errorf("Get() got error %v, want %v", err, ErrNotFound)
+	}
+	if _, err := s.Update(ctx, missing, bson.M{"title": "missing"}, AnyRevision); err != ErrNotFound {
+		t.Errorf("Update() got error %v, want %v", err, ErrNotFound)
+	}
+	if _, err := s.Delete(ctx, missing, AnyRevision); err != ErrNotFound {
+		t.Errorf("Delete() got error %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryBlogStore_RevisionMismatch(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryBlogStore()
+
+	item := &models.BlogItem{Title: "v1", Revision: 1}
+	if err := s.Create(ctx, item); err != nil {
+		t.Fatalf("Create() had unexpected error: %v", err)
+	}
+
+	if _, err := s.Update(ctx, item.ID, bson.M{"title": "v2"}, 1); err != nil {
+		t.Fatalf("Update(expected 1) had unexpected error: %v", err)
+	}
+	if _, err := s.Update(ctx, item.ID, bson.M{"title": "stale"}, 1); err != ErrRevisionMismatch {
+		t.Errorf("Update(expected 1) at revision 2 got error %v, want %v", err, ErrRevisionMismatch)
+	}
+	if _, err := s.Delete(ctx, item.ID, 1); err != ErrRevisionMismatch {
+		t.Errorf("Delete(expected 1) at revision 2 got error %v, want %v", err, ErrRevisionMismatch)
+	}
+	if _, err := s.Delete(ctx, item.ID, 2); err != nil {
+		t.Errorf("Delete(expected 2) had unexpected error: %v", err)
+	}
+}
+
+func TestMemoryBlogStore_Put(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryBlogStore()
+
+	item := &models.BlogItem{ID: primitive.NewObjectID(), Title: "v1", Revision: 1}
+	if created, err := s.Put(ctx, item); err != nil || !created {
+		t.Fatalf("Put(new) got %v, %v, want true, nil", created, err)
+	}
+	item.Title, item.Revision = "v5", 5
+	if created, err := s.Put(ctx, item); err != nil || created {
+		t.Fatalf("Put(existing) got %v, %v, want false, nil", created, err)
+	}
+
+	got, err := s.Get(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("Get() had unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, item) {
+		t.Errorf("Get() got %v, want %v", got, item)
+	}
+}
+
+func TestMemoryBlogStore_ListInInsertionOrder(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryBlogStore()
+
+	titles := []string{"first", "second", "third"}
+	for _, title := range titles {
+		if err := s.Create(ctx, &models.BlogItem{Title: title}); err != nil {
+			t.Fatalf("Create(%q) had unexpected error: %v", title, err)
+		}
+	}
+
+	var got []string
+	err := s.List(ctx, ListOptions{}, func(item *models.BlogItem) error {
+		got = append(got, item.Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List() had unexpected error: %v", err)
+	}
+	if len(got) != len(titles) {
+		t.Fatalf("List() visited %d blogs, want %d", len(got), len(titles))
+	}
+	for i := range titles {
+		if got[i] != titles[i] {
+			t.Errorf("List() item %d got %q, want %q", i, got[i], titles[i])
+		}
+	}
+}
+
+func TestMemoryBlogStore_ListOptions(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryBlogStore()
+
+	items := []*models.BlogItem{
+		{AuthorID: "Khoi", Title: "k1", Tags: []string{"go", "grpc"}},
+		{AuthorID: "John", Title: "j1", Tags: []string{"go"}},
+		{AuthorID: "Khoi", Title: "k2"},
+		{AuthorID: "Khoi", Title: "k3", Tags: []string{"grpc"}},
+	}
+	for _, item := range items {
+		if err := s.Create(ctx, item); err != nil {
+			t.Fatalf("Create(%q) had unexpected error: %v", item.Title, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"author", ListOptions{AuthorID: "Khoi"}, []string{"k1", "k2", "k3"}},
+		{"descending", ListOptions{Descending: true}, []string{"k3", "k2", "j1", "k1"}},
+		{"limit", ListOptions{Limit: 2}, []string{"k1", "j1"}},
+		{"after", ListOptions{After: items[1].ID}, []string{"k2", "k3"}},
+		{"after descending", ListOptions{After: items[2].ID, Descending: true, AuthorID: "Khoi"}, []string{"k1"}},
+		{"tag", ListOptions{Tags: []string{"grpc"}}, []string{"k1", "k3"}},
+		{"all tags", ListOptions{Tags: []string{"grpc", "go"}}, []string{"k1"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		err := s.List(ctx, tt.opts, func(item *models.BlogItem) error {
+			got = append(got, item.Title)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: List() had unexpected error: %v", tt.name, err)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: List() got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryBlogStore_Tags(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryBlogStore()
+
+	items := []*models.BlogItem{
+		{Title: "a", Tags: []string{"go", "grpc"}},
+		{Title: "b", Tags: []string{"grpc"}},
+		{Title: "c", Tags: []string{"mongo", "grpc"}},
+		{Title: "trashed", Tags: []string{"mongo"}, DeletedAt: time.Now()},
+	}
+	for _, item := range items {
+		if err := s.Create(ctx, item); err != nil {
+			t.Fatalf("Create(%q) had unexpected error: %v", item.Title, err)
+		}
+	}
+
+	got, err := s.Tags(ctx, 0)
+	if err != nil {
+		t.Fatalf("Tags() had unexpected error: %v", err)
+	}
+	want := []TagCount{{"grpc", 3}, {"go", 1}, {"mongo", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() got %v, want %v", got, want)
+	}
+
+	if got, _ := s.Tags(ctx, 1); len(got) != 1 {
+		t.Errorf("Tags(limit 1) got %v, want one tag", got)
+	}
+}
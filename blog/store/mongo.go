@@ -0,0 +1,468 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mirageruler/grpc-go-course/blog/models"
+	"github.com/mirageruler/grpc-go-course/blog/search"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoBlogStore is a BlogStore backed by a MongoDB collection.
+type MongoBlogStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoBlogStore returns a BlogStore that keeps blogs in collection.
+func NewMongoBlogStore(collection *mongo.Collection) *MongoBlogStore {
+	return &MongoBlogStore{collection: collection}
+}
+
+// EnsureIndexes creates the text index SearchBlogs relies on and the index
+// on tags. It is a no-op when the indexes already exist.
+func (s *MongoBlogStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "content", Value: "text"}},
+			Options: options.Index().
+				SetName("title_content_text").
+				SetWeights(bson.M{"title": search.TitleWeight, "content": 1}),
+		},
+		{
+			Keys: bson.D{{Key: "tags", Value: 1}},
+		},
+	})
+	return err
+}
+
+func (s *MongoBlogStore) Create(ctx context.Context, item *models.BlogItem) error {
+	result, err := s.collection.InsertOne(ctx, item)
+	if err != nil {
+		return err
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("cannot convert %v to primitive.ObjectID", result.InsertedID)
+	}
+	item.ID = oid
+
+	return nil
+}
+
+func (s *MongoBlogStore) CreateMany(ctx context.Context, items []*models.BlogItem) []error {
+	errs := make([]error, len(items))
+	if len(items) == 0 {
+		return errs
+	}
+
+	docs := make([]interface{}, len(items))
+	for i, item := range items {
+		docs[i] = item
+	}
+
+	// unordered, so one failing document does not stop the others
+	result, err := s.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if bwe, ok := err.(mongo.BulkWriteException); ok && len(bwe.WriteErrors) > 0 {
+		for _, we := range bwe.WriteErrors {
+			errs[we.Index] = we
+		}
+	} else if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	// InsertedIDs holds the ID generated for every document, in order
+	for i, item := range items {
+		if errs[i] != nil {
+			continue
+		}
+		oid, ok := result.InsertedIDs[i].(primitive.ObjectID)
+		if !ok {
+			errs[i] = fmt.Errorf("cannot convert %v to primitive.ObjectID", result.InsertedIDs[i])
+			continue
+		}
+		item.ID = oid
+	}
+
+	return errs
+}
+
+func (s *MongoBlogStore) Get(ctx context.Context, id primitive.ObjectID) (*models.BlogItem, error) {
+	data := &models.BlogItem{}
+	if err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(data); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *MongoBlogStore) GetMany(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*models.BlogItem, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	found := make(map[primitive.ObjectID]*models.BlogItem, len(ids))
+	for cursor.Next(ctx) {
+		data := &models.BlogItem{}
+		if err := cursor.Decode(data); err != nil {
+			return nil, err
+		}
+		found[data.ID] = data
+	}
+
+	return found, cursor.Err()
+}
+
+func (s *MongoBlogStore) Put(ctx context.Context, item *models.BlogItem) (bool, error) {
+	opts := options.Replace().SetUpsert(true)
+	result, err := s.collection.ReplaceOne(ctx, bson.M{"_id": item.ID}, item, opts)
+	if err != nil {
+		return false, err
+	}
+
+	return result.UpsertedCount > 0, nil
+}
+
+func (s *MongoBlogStore) Update(ctx context.Context, id primitive.ObjectID, set bson.M, expectedRevision int64) (*models.BlogItem, error) {
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"revision": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	data := &models.BlogItem{}
+	err := s.collection.FindOneAndUpdate(ctx, revisionFilter(id, expectedRevision), update, opts).Decode(data)
+	if err == mongo.ErrNoDocuments {
+		return nil, s.missError(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *MongoBlogStore) Delete(ctx context.Context, id primitive.ObjectID, expectedRevision int64) (*models.BlogItem, error) {
+	data := &models.BlogItem{}
+	err := s.collection.FindOneAndDelete(ctx, revisionFilter(id, expectedRevision)).Decode(data)
+	if err == mongo.ErrNoDocuments {
+		return nil, s.missError(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// revisionFilter matches the blog with the given ID, and only at
+// expectedRevision unless that is AnyRevision.
+func revisionFilter(id primitive.ObjectID, expectedRevision int64) bson.M {
+	filter := bson.M{"_id": id}
+	if expectedRevision != AnyRevision {
+		filter["revision"] = expectedRevision
+	}
+	return filter
+}
+
+// missError explains why a conditional write matched no document: either the
+// blog does not exist or it is at another revision.
+func (s *MongoBlogStore) missError(ctx context.Context, id primitive.ObjectID) error {
+	n, err := s.collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return ErrRevisionMismatch
+}
+
+func (s *MongoBlogStore) List(ctx context.Context, opts ListOptions, fn func(*models.BlogItem) error) error {
+	filter := bson.M{}
+	if opts.AuthorID != "" {
+		filter["author_id"] = opts.AuthorID
+	}
+	if len(opts.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": opts.Tags}
+	}
+
+	order, after := 1, "$gt"
+	if opts.Descending {
+		order, after = -1, "$lt"
+	}
+	if !opts.After.IsZero() {
+		filter["_id"] = bson.M{after: opts.After}
+	}
+	switch opts.Trash {
+	case HideTrashed:
+		// a null query also matches blogs written before deleted_at existed
+		filter["deleted_at"] = nil
+	case OnlyTrashed:
+		filter["deleted_at"] = bson.M{"$ne": nil}
+	}
+	if !opts.DeletedBefore.IsZero() {
+		filter["deleted_at"] = bson.M{"$lt": opts.DeletedBefore}
+	}
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "_id", Value: order}})
+	if opts.Limit > 0 {
+		findOpts.SetLimit(int64(opts.Limit))
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		data := &models.BlogItem{}
+		if err := cursor.Decode(data); err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func (s *MongoBlogStore) Search(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	opts := options.Find().SetProjection(score).SetSort(score)
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	filter := bson.M{
+		"$text":      bson.M{"$search": query},
+		"deleted_at": nil,
+	}
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hits []SearchHit
+	for cursor.Next(ctx) {
+		data := struct {
+			models.BlogItem `bson:",inline"`
+			Score           float64 `bson:"score"`
+		}{}
+		if err := cursor.Decode(&data); err != nil {
+			return nil, err
+		}
+		hits = append(hits, SearchHit{Item: &data.BlogItem, Score: data.Score})
+	}
+
+	return hits, cursor.Err()
+}
+
+func (s *MongoBlogStore) Tags(ctx context.Context, limit int) ([]TagCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"deleted_at": nil}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tags []TagCount
+	for cursor.Next(ctx) {
+		data := struct {
+			Tag   string `bson:"_id"`
+			Count int    `bson:"count"`
+		}{}
+		if err := cursor.Decode(&data); err != nil {
+			return nil, err
+		}
+		tags = append(tags, TagCount{Tag: data.Tag, Count: data.Count})
+	}
+
+	return tags, cursor.Err()
+}
+
+// MongoHistoryStore is a HistoryStore backed by a MongoDB collection.
+type MongoHistoryStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoHistoryStore returns a HistoryStore that keeps revisions in collection.
+func NewMongoHistoryStore(collection *mongo.Collection) *MongoHistoryStore {
+	return &MongoHistoryStore{collection: collection}
+}
+
+// EnsureIndexes creates the index revisions are looked up by.
+func (s *MongoHistoryStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "blog_id", Value: 1}, {Key: "revision", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (s *MongoHistoryStore) Append(ctx context.Context, item *models.BlogItem) error {
+	_, err := s.collection.InsertOne(ctx, newRevision(item))
+	return err
+}
+
+func (s *MongoHistoryStore) List(ctx context.Context, blogID primitive.ObjectID) ([]*models.BlogRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: -1}})
+	cursor, err := s.collection.Find(ctx, bson.M{"blog_id": blogID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []*models.BlogRevision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (s *MongoHistoryStore) Get(ctx context.Context, blogID primitive.ObjectID, revision int64) (*models.BlogRevision, error) {
+	data := &models.BlogRevision{}
+	err := s.collection.FindOne(ctx, bson.M{"blog_id": blogID, "revision": revision}).Decode(data)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *MongoHistoryStore) DeleteAll(ctx context.Context, blogID primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(ctx, bson.M{"blog_id": blogID})
+	return err
+}
+
+// MongoCommentStore is a CommentStore backed by a MongoDB collection.
+type MongoCommentStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoCommentStore returns a CommentStore that keeps comments in collection.
+func NewMongoCommentStore(collection *mongo.Collection) *MongoCommentStore {
+	return &MongoCommentStore{collection: collection}
+}
+
+// EnsureIndexes creates the index comments are listed by.
+func (s *MongoCommentStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "blog_id", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	return err
+}
+
+func (s *MongoCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	result, err := s.collection.InsertOne(ctx, comment)
+	if err != nil {
+		return err
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("cannot convert %v to primitive.ObjectID", result.InsertedID)
+	}
+	comment.ID = oid
+
+	return nil
+}
+
+func (s *MongoCommentStore) Get(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	data := &models.Comment{}
+	if err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(data); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *MongoCommentStore) Update(ctx context.Context, id primitive.ObjectID, set bson.M) (*models.Comment, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	data := &models.Comment{}
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": set}, opts).Decode(data)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *MongoCommentStore) List(ctx context.Context, opts CommentListOptions, fn func(*models.Comment) error) error {
+	filter := bson.M{"blog_id": opts.BlogID}
+	if !opts.ParentID.IsZero() {
+		filter["parent_id"] = opts.ParentID
+	}
+	if !opts.After.IsZero() {
+		filter["_id"] = bson.M{"$gt": opts.After}
+	}
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if opts.Limit > 0 {
+		findOpts.SetLimit(int64(opts.Limit))
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		data := &models.Comment{}
+		if err := cursor.Decode(data); err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func (s *MongoCommentStore) DeleteMany(ctx context.Context, ids []primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+func (s *MongoCommentStore) DeleteByBlog(ctx context.Context, blogID primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(ctx, bson.M{"blog_id": blogID})
+	return err
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/metrics"
+	"github.com/mirageruler/grpc-go-course/tracing"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// CommandMonitor returns a monitor for mongo.Client that records how long
+// every MongoDB command takes in r, labelled with the command, such as find
+// or update, and with its outcome, success or failure. The commands run
+// within a trace, such as the ones of a traced call, also get a span of t.
+func CommandMonitor(r *metrics.Registry, t *tracing.Tracer) *event.CommandMonitor {
+	durations := r.Histogram(
+		"mongodb_command_duration_seconds",
+		"Duration in seconds of the MongoDB commands run by the stores.",
+		metrics.DefaultBuckets,
+		"command", "outcome",
+	)
+
+	// the spans of the commands in flight, by their request ID
+	var mu sync.Mutex
+	spans := make(map[int64]*tracing.Span)
+	end := func(requestID int64, err error) {
+		mu.Lock()
+		s, ok := spans[requestID]
+		delete(spans, requestID)
+		mu.Unlock()
+		if ok {
+			s.End(err)
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if !tracing.SpanContextFromContext(ctx).IsValid() {
+				return
+			}
+			_, s := t.Start(ctx, "mongodb."+e.CommandName, tracing.Client)
+			s.SetAttribute("db.system", "mongodb")
+			s.SetAttribute("db.name", e.DatabaseName)
+			s.SetAttribute("db.operation", e.CommandName)
+			mu.Lock()
+			spans[e.RequestID] = s
+			mu.Unlock()
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			durations.Observe(time.Duration(e.DurationNanos).Seconds(), e.CommandName, "success")
+			end(e.RequestID, nil)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			durations.Observe(time.Duration(e.DurationNanos).Seconds(), e.CommandName, "failure")
+			end(e.RequestID, errors.New(e.Failure))
+		},
+	}
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/metrics"
+	"github.com/mirageruler/grpc-go-course/tracing"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestCommandMonitor(t *testing.T) {
+	var spans bytes.Buffer
+	tracer := tracing.New("blog", tracing.NewJSONExporter(&spans))
+	r := metrics.NewRegistry()
+	m := CommandMonitor(r, tracer)
+
+	// a command of a traced call, and one outside of any trace
+	ctx, call := tracer.Start(context.Background(), "blog.BlogService/ReadBlog", tracing.Server)
+	m.Started(ctx, &event.CommandStartedEvent{CommandName: "find", DatabaseName: "mydb", RequestID: 1})
+	m.Started(context.Background(), &event.CommandStartedEvent{CommandName: "ping", RequestID: 2})
+	m.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1}, Failure: "timed out"})
+	m.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "ping", RequestID: 2}})
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(spans.Bytes(), &got); err != nil {
+		t.Fatalf("CommandMonitor() exported %s, want the span of find only", spans.String())
+	}
+	if got["name"] != "mongodb.find" || got["parent_id"] != call.SpanID.String() || got["error"] != "timed out" {
+		t.Errorf("CommandMonitor() exported %s, want a failed mongodb.find child of the call", spans.String())
+	}
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+	for _, want := range []string{
+		`mongodb_command_duration_seconds_count{command="find",outcome="failure"} 1`,
+		`mongodb_command_duration_seconds_count{command="ping",outcome="success"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("CommandMonitor() recorded\n%s\nwant it to contain %s", buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotFound is returned when no blog exists with the requested ID.
+	ErrNotFound = errors.New("blog not found")
+
+	// ErrRevisionMismatch is returned by conditional writes when the stored
+	// blog's revision differs from the expected one.
+	ErrRevisionMismatch = errors.New("blog revision mismatch")
+)
+
+// AnyRevision disables the revision check of conditional writes.
+const AnyRevision int64 = 0
+
+// BlogStore persists blog items. Implementations must be safe for concurrent use.
+type BlogStore interface {
+	// Create inserts a new blog and sets item.ID to the generated ID.
+	Create(ctx context.Context, item *models.BlogItem) error
+
+	// Get returns the blog with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id primitive.ObjectID) (*models.BlogItem, error)
+
+	// Put writes item under its own ID, inserting it or overwriting the blog
+	// already stored with that ID regardless of its revision. It reports
+	// whether the blog was inserted.
+	Put(ctx context.Context, item *models.BlogItem) (bool, error)
+
+	// Update sets the given fields, keyed by their bson names, on the blog with
+	// the given ID and increments its revision, returning the updated blog, or
+	// returns ErrNotFound. Unless expectedRevision is AnyRevision, it returns
+	// ErrRevisionMismatch when the stored blog is at a different revision.
+	Update(ctx context.Context, id primitive.ObjectID, set bson.M, expectedRevision int64) (*models.BlogItem, error)
+
+	// Delete removes the blog with the given ID and returns it as it was, or
+	// returns ErrNotFound. expectedRevision is checked the same way as in Update.
+	Delete(ctx context.Context, id primitive.ObjectID, expectedRevision int64) (*models.BlogItem, error)
+
+	// List calls fn for every blog matching opts in ID order, stopping at the first error.
+	List(ctx context.Context, opts ListOptions, fn func(*models.BlogItem) error) error
+
+	// CreateMany inserts several blogs at once, setting their IDs. It returns
+	// one error per item, nil for the blogs that were created.
+	CreateMany(ctx context.Context, items []*models.BlogItem) []error
+
+	// GetMany returns the blogs with the given IDs that exist, keyed by ID.
+	GetMany(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*models.BlogItem, error)
+
+	// Search returns up to limit blogs outside the trash whose title or
+	// content contains any word of query, most relevant first.
+	Search(ctx context.Context, query string, limit int) ([]SearchHit, error)
+
+	// Tags counts the blogs outside the trash that have each tag, returning
+	// up to limit tags, most used first and ties by tag. A limit of 0 means
+	// no limit.
+	Tags(ctx context.Context, limit int) ([]TagCount, error)
+}
+
+// SearchHit is a blog matching a search query.
+type SearchHit struct {
+	Item *models.BlogItem
+
+	// Score ranks the hit against the other hits of the same search.
+	Score float64
+}
+
+// TagCount is the number of blogs that have a tag.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// ListOptions filters and orders the blogs visited by BlogStore.List.
+type ListOptions struct {
+	// AuthorID restricts the listing to one author when non-empty.
+	AuthorID string
+
+	// Tags restricts the listing to blogs that have every one of these tags.
+	Tags []string
+
+	// After skips blogs up to and including this ID in the listing order.
+	// The zero ObjectID starts from the beginning.
+	After primitive.ObjectID
+
+	// Descending lists the newest blogs first.
+	Descending bool
+
+	// Limit caps the number of blogs visited, 0 means no limit.
+	Limit int
+
+	// Trash selects blogs by whether they are in the trash.
+	Trash TrashFilter
+
+	// DeletedBefore, when non-zero, only keeps blogs trashed before this time.
+	DeletedBefore time.Time
+}
+
+// TrashFilter selects blogs by whether they are in the trash, that is whether
+// their DeletedAt is set.
+type TrashFilter int
+
+const (
+	HideTrashed TrashFilter = iota
+	OnlyTrashed
+	WithTrashed
+)
+
+// keepTrash reports whether item passes the trash filters of opts.
+func (opts ListOptions) keepTrash(item *models.BlogItem) bool {
+	trashed := !item.DeletedAt.IsZero()
+	switch {
+	case opts.Trash == HideTrashed && trashed:
+		return false
+	case opts.Trash == OnlyTrashed && !trashed:
+		return false
+	case !opts.DeletedBefore.IsZero() && !(trashed && item.DeletedAt.Before(opts.DeletedBefore)):
+		return false
+	}
+	return true
+}
+
+// keepTags reports whether item has all the tags opts asks for.
+func (opts ListOptions) keepTags(item *models.BlogItem) bool {
+	for _, want := range opts.Tags {
+		found := false
+		for _, tag := range item.Tags {
+			if tag == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// HistoryStore keeps the past versions of blogs. Implementations must be safe
+// for concurrent use.
+type HistoryStore interface {
+	// Append archives a version of a blog.
+	Append(ctx context.Context, item *models.BlogItem) error
+
+	// List returns the archived versions of a blog, newest first.
+	List(ctx context.Context, blogID primitive.ObjectID) ([]*models.BlogRevision, error)
+
+	// Get returns one archived version of a blog, or ErrNotFound.
+	Get(ctx context.Context, blogID primitive.ObjectID, revision int64) (*models.BlogRevision, error)
+
+	// DeleteAll removes every archived version of a blog.
+	DeleteAll(ctx context.Context, blogID primitive.ObjectID) error
+}
+
+func newRevision(item *models.BlogItem) *models.BlogRevision {
+	return &models.BlogRevision{
+		BlogID:   item.ID,
+		Revision: item.Revision,
+		Blog:     *item,
+	}
+}
+
+// CommentListOptions selects and pages the comments passed to CommentStore.List.
+type CommentListOptions struct {
+	// BlogID selects the comments on one blog.
+	BlogID primitive.ObjectID
+	// ParentID, if not zero, only selects the replies to that comment.
+	ParentID primitive.ObjectID
+	// After, if not zero, skips comments up to and including this ID.
+	After primitive.ObjectID
+	// Limit caps the number of comments listed, 0 meaning no limit.
+	Limit int
+}
+
+// CommentStore persists the comments on blogs.
+type CommentStore interface {
+	// Create inserts comment and sets its ID.
+	Create(ctx context.Context, comment *models.Comment) error
+	// Get returns ErrNotFound if there is no comment with the given ID.
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Comment, error)
+	// Update applies set to the comment with the given ID and returns it as
+	// updated, or ErrNotFound.
+	Update(ctx context.Context, id primitive.ObjectID, set bson.M) (*models.Comment, error)
+	// List calls fn for the comments matching opts, oldest first.
+	List(ctx context.Context, opts CommentListOptions, fn func(*models.Comment) error) error
+	// DeleteMany removes the comments with the given IDs that exist.
+	DeleteMany(ctx context.Context, ids []primitive.ObjectID) error
+	// DeleteByBlog removes every comment on a blog.
+	DeleteByBlog(ctx context.Context, blogID primitive.ObjectID) error
+}
@@ -0,0 +1,217 @@
+// Package validate checks gRPC requests against declarative field rules and
+// reports the fields that break them as errdetails.BadRequest violations.
+package validate
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Rule checks the value of a field, returning what is wrong with it or "" if
+// it is valid. set reports whether the field is set in the message, which for
+// proto3 scalars means it is not the zero value.
+type Rule func(value protoreflect.Value, field protoreflect.FieldDescriptor, set bool) string
+
+// Required rejects fields that are not set, or lists that are empty.
+func Required() Rule {
+	return func(value protoreflect.Value, field protoreflect.FieldDescriptor, set bool) string {
+		if !set {
+			return "is required"
+		}
+		return ""
+	}
+}
+
+// MaxLen rejects strings longer than n characters.
+func MaxLen(n int) Rule {
+	return func(value protoreflect.Value, field protoreflect.FieldDescriptor, set bool) string {
+		if utf8.RuneCountInString(value.String()) > n {
+			return fmt.Sprintf("must be at most %d characters long", n)
+		}
+		return ""
+	}
+}
+
+// MaxItems rejects lists with more than n items.
+func MaxItems(n int) Rule {
+	return func(value protoreflect.Value, field protoreflect.FieldDescriptor, set bool) string {
+		if value.List().Len() > n {
+			return fmt.Sprintf("must have at most %d items", n)
+		}
+		return ""
+	}
+}
+
+// Min rejects integers smaller than n.
+func Min(n int64) Rule {
+	return func(value protoreflect.Value, field protoreflect.FieldDescriptor, set bool) string {
+		if value.Int() < n {
+			return fmt.Sprintf("must be at least %d", n)
+		}
+		return ""
+	}
+}
+
+// DefinedEnum rejects enum values that are not declared by the enum, such as
+// values sent by a client built against a newer version of the API.
+func DefinedEnum() Rule {
+	return func(value protoreflect.Value, field protoreflect.FieldDescriptor, set bool) string {
+		if field.Enum().Values().ByNumber(value.Enum()) == nil {
+			return fmt.Sprintf("has unknown value %d", value.Enum())
+		}
+		return ""
+	}
+}
+
+// ObjectID rejects strings that are set but are not a MongoDB ObjectID in hex.
+func ObjectID() Rule {
+	return func(value protoreflect.Value, field protoreflect.FieldDescriptor, set bool) string {
+		id := value.String()
+		if id == "" {
+			return ""
+		}
+		if _, err := hex.DecodeString(id); err != nil || len(id) != 24 {
+			return "must be a 24 character hexadecimal ID"
+		}
+		return ""
+	}
+}
+
+// Each applies rule to every item of a list.
+func Each(rule Rule) Rule {
+	return func(value protoreflect.Value, field protoreflect.FieldDescriptor, set bool) string {
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			if problem := rule(list.Get(i), field, true); problem != "" {
+				return fmt.Sprintf("item %d %s", i, problem)
+			}
+		}
+		return ""
+	}
+}
+
+// Fields maps dotted field paths of a message, like "blog.title", to the rules
+// their value must follow. Paths go through singular message fields only.
+type Fields map[string][]Rule
+
+// With returns the rules of both f and other.
+func (f Fields) With(other Fields) Fields {
+	merged := make(Fields, len(f)+len(other))
+	for path, rules := range f {
+		merged[path] = append(merged[path], rules...)
+	}
+	for path, rules := range other {
+		merged[path] = append(merged[path], rules...)
+	}
+	return merged
+}
+
+// Prefix returns the rules of f for a message found at field path prefix.
+func (f Fields) Prefix(prefix string) Fields {
+	prefixed := make(Fields, len(f))
+	for path, rules := range f {
+		prefixed[prefix+"."+path] = rules
+	}
+	return prefixed
+}
+
+// Violations returns a violation for every rule msg breaks, sorted by field.
+// It panics if a path does not name a field of msg, as that is a bug in the
+// rules rather than in the request.
+func (f Fields) Violations(msg proto.Message) []*errdetails.BadRequest_FieldViolation {
+	paths := make([]string, 0, len(f))
+	for path := range f {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, path := range paths {
+		value, field, set := lookup(msg.ProtoReflect(), path)
+		for _, rule := range f[path] {
+			if problem := rule(value, field, set); problem != "" {
+				violations = append(violations, &errdetails.BadRequest_FieldViolation{
+					Field:       path,
+					Description: problem,
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// Validate returns nil if msg follows the rules, or an InvalidArgument status
+// error with a BadRequest detail listing the violations.
+func (f Fields) Validate(msg proto.Message) error {
+	return invalid(f.Violations(msg))
+}
+
+// lookup returns the value at path in msg. Fields under an unset message read
+// as their zero value and are not set.
+func lookup(msg protoreflect.Message, path string) (protoreflect.Value, protoreflect.FieldDescriptor, bool) {
+	names := strings.Split(path, ".")
+	set := true
+	for i, name := range names {
+		field := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			panic(fmt.Sprintf("validate: %s has no field %q", msg.Descriptor().FullName(), strings.Join(names[:i+1], ".")))
+		}
+		set = set && msg.Has(field)
+		value := msg.Get(field)
+		if i == len(names)-1 {
+			return value, field, set
+		}
+		if field.Kind() != protoreflect.MessageKind || field.IsList() || field.IsMap() {
+			panic(fmt.Sprintf("validate: %s is not a singular message field", strings.Join(names[:i+1], ".")))
+		}
+		msg = value.Message()
+	}
+
+	panic("validate: empty path")
+}
+
+func invalid(violations []*errdetails.BadRequest_FieldViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	problems := make([]string, len(violations))
+	for i, v := range violations {
+		problems[i] = v.GetField() + " " + v.GetDescription()
+	}
+	return rpcerr.New(
+		codes.InvalidArgument,
+		"invalid request: "+strings.Join(problems, "; "),
+		rpcerr.BadRequest(violations...),
+	)
+}
+
+// Rules maps request messages to the rules their fields must follow.
+// Messages without rules are always valid.
+type Rules map[protoreflect.FullName]Fields
+
+// For adds the rules of the requests of the same type as msg.
+func (r Rules) For(msg proto.Message, fields Fields) Rules {
+	r[msg.ProtoReflect().Descriptor().FullName()] = fields
+	return r
+}
+
+// Validate checks msg against the rules for its type, see Fields.Validate.
+func (r Rules) Validate(msg proto.Message) error {
+	fields, ok := r[msg.ProtoReflect().Descriptor().FullName()]
+	if !ok {
+		return nil
+	}
+	return fields.Validate(msg)
+}
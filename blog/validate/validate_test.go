@@ -0,0 +1,115 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+// the well-known Api message has strings, lists and a nested message to test with
+var apiFields = Fields{
+	"name":                     {Required(), MaxLen(5)},
+	"version":                  {ObjectID()},
+	"methods":                  {MaxItems(1)},
+	"source_context":           {Required()},
+	"source_context.file_name": {Required()},
+}
+
+func TestFields_Violations(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *apipb.Api
+		want []string
+	}{
+		{
+			"valid",
+			&apipb.Api{Name: "blog", Version: "5bdc29e661b75adcac496cf4", SourceContext: &sourcecontextpb.SourceContext{FileName: "blog.proto"}},
+			nil,
+		},
+		{
+			"empty",
+			&apipb.Api{},
+			[]string{"name is required", "source_context is required", "source_context.file_name is required"},
+		},
+		{
+			"too long",
+			&apipb.Api{Name: "blogs!", Version: "v1", Methods: make([]*apipb.Method, 2), SourceContext: &sourcecontextpb.SourceContext{FileName: "blog.proto"}},
+			[]string{"methods must have at most 1 items", "name must be at most 5 characters long", "version must be a 24 character hexadecimal ID"},
+		},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, v := range apiFields.Violations(tt.msg) {
+			got = append(got, v.GetField()+" "+v.GetDescription())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("Violations(%s) got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFields_Each(t *testing.T) {
+	fields := Fields{"paths": {Each(MaxLen(3))}}
+	violations := fields.Violations(&fieldmaskpb.FieldMask{Paths: []string{"id", "title"}})
+	if len(violations) != 1 || violations[0].GetDescription() != "item 1 must be at most 3 characters long" {
+		t.Errorf("Violations() got %v, want item 1 too long", violations)
+	}
+}
+
+func TestFields_DefinedEnum(t *testing.T) {
+	fields := Fields{"syntax": {DefinedEnum()}}
+	if violations := fields.Violations(&apipb.Api{Syntax: typepb.Syntax_SYNTAX_PROTO3}); len(violations) != 0 {
+		t.Errorf("Violations(proto3) got %v, want none", violations)
+	}
+	violations := fields.Violations(&apipb.Api{Syntax: 7})
+	if len(violations) != 1 || violations[0].GetDescription() != "has unknown value 7" {
+		t.Errorf("Violations(7) got %v, want unknown value", violations)
+	}
+}
+
+func TestFields_PrefixWith(t *testing.T) {
+	fields := Fields{"file_name": {MaxLen(3)}}.Prefix("source_context").With(Fields{"source_context.file_name": {Required()}})
+	violations := fields.Violations(&apipb.Api{})
+	if len(violations) != 1 || violations[0].GetField() != "source_context.file_name" {
+		t.Errorf("Violations() got %v, want source_context.file_name required", violations)
+	}
+}
+
+func TestFields_Validate(t *testing.T) {
+	if err := apiFields.Validate(&apipb.Api{Name: "blog", SourceContext: &sourcecontextpb.SourceContext{FileName: "b"}}); err != nil {
+		t.Errorf("Validate(valid) got error %v", err)
+	}
+
+	err := apiFields.Validate(&apipb.Api{})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("Validate(empty) got code %v, want %v", st.Code(), codes.InvalidArgument)
+	}
+	var badRequest *errdetails.BadRequest
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			badRequest = br
+		}
+	}
+	if got := len(badRequest.GetFieldViolations()); got != 3 {
+		t.Errorf("Validate(empty) got %d field violations, want 3", got)
+	}
+}
+
+func TestRules_Validate(t *testing.T) {
+	rules := Rules{}.For(&apipb.Api{}, apiFields)
+	if err := rules.Validate(&apipb.Api{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Validate(Api) got %v, want InvalidArgument", err)
+	}
+	if err := rules.Validate(&apipb.Method{}); err != nil {
+		t.Errorf("Validate(Method) without rules got %v, want nil", err)
+	}
+}
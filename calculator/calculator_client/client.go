@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"time"
 
 	"github.com/mirageruler/grpc-go-course/calculator/calculatorpb"
+	"github.com/mirageruler/grpc-go-course/metrics"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+	"github.com/mirageruler/grpc-go-course/tracing"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -20,7 +24,19 @@ func main() {
 	fmt.Println("--------------------------------------------------------------------")
 
 	dialOption := grpc.WithTransportCredentials(insecure.NewCredentials())
-	conn, err := grpc.Dial("localhost:50051", dialOption)
+	// every call starts a trace, which the server joins
+	tracing.DefaultTracer.Configure("calculator_client", tracing.NewJSONExporter(os.Stdout))
+	clientMetrics := metrics.NewClientMetrics(metrics.DefaultRegistry)
+	conn, err := grpc.Dial("localhost:50051", dialOption,
+		grpc.WithChainUnaryInterceptor(
+			tracing.UnaryClientInterceptor(tracing.DefaultTracer),
+			metrics.UnaryClientInterceptor(clientMetrics),
+		),
+		grpc.WithChainStreamInterceptor(
+			tracing.StreamClientInterceptor(tracing.DefaultTracer),
+			metrics.StreamClientInterceptor(clientMetrics),
+		),
+	)
 	if err != nil {
 		log.Fatalf("could not connect: %v", err)
 	}
@@ -172,6 +188,9 @@ func doHandleErrorUnaryCall(c calculatorpb.CalculatorServiceClient, number int32
 			fmt.Println(errStatus.Code())
 			if errStatus.Code() == codes.InvalidArgument {
 				fmt.Println("We probaly sent a negative number!")
+				for _, v := range rpcerr.ViolationsOf(err) {
+					fmt.Printf("Field %s %s\n", v.GetField(), v.GetDescription())
+				}
 				return
 			}
 		} else {
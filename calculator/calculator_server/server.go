@@ -1,130 +1,37 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
 	"log"
-	"math"
 	"net"
-	"time"
+
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorservice"
+	"github.com/mirageruler/grpc-go-course/config"
+	"github.com/mirageruler/grpc-go-course/healthcheck"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
-	"google.golang.org/grpc/status"
-
-	"github.com/mirageruler/grpc-go-course/calculator/calculatorpb"
 )
 
-type server struct{}
-
-func (*server) Sum(ctx context.Context, req *calculatorpb.SumRequest) (*calculatorpb.SumResponse, error) {
-	fmt.Printf("Sum func was invoked with %v\n", req)
-
-	firstNum := req.GetFirstNumber()
-	secondNum := req.GetSecondNumber()
-	result := firstNum + secondNum
-	res := calculatorpb.SumResponse{
-		SumResult: result,
-	}
-
-	return &res, nil
-}
-
-func (*server) PrimeNumberDecomposition(req *calculatorpb.PrimeNumberDecompositionRequest, stream calculatorpb.CalculatorService_PrimeNumberDecompositionServer) error {
-	fmt.Printf("PrimeNumberDecomposition func was invoked with %v\n", req)
-
-	number := req.GetNumber()
-	divisor := int64(2)
-
-	for number > 1 {
-		if number%divisor == 0 {
-			res := &calculatorpb.PrimeNumberDecompositionResponse{
-				PrimeFactor: divisor,
-			}
-			stream.Send(res)
-			time.Sleep(time.Second)
-			number = number / divisor
-		} else {
-			divisor++
-			fmt.Printf("Divisor has increased to %v\n", divisor)
-		}
-	}
-
-	return nil
-}
-
-func (*server) ComputeAverage(stream calculatorpb.CalculatorService_ComputeAverageServer) error {
-	fmt.Printf("Received ComputeAverage RPC\n")
-
-	sum := 0
-	count := 0
-
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			// we have finished reading the client stream
-			average := float64(sum) / float64(count)
-			return stream.SendAndClose(&calculatorpb.ComputeAverageResponse{
-				Average: average,
-			})
-		}
-		if err != nil {
-			log.Fatalf("Error while reading client stream: %v", err)
-		}
+func main() {
+	fmt.Println("Calculator Server")
 
-		sum += int(req.GetNumber())
-		count++
+	loader := config.Register(flag.CommandLine, "CALCULATOR", config.Defaults())
+	flag.Parse()
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
 	}
-
-}
-
-func (*server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumServer) error {
-	fmt.Printf("Received FindMaximum RPC\n")
-
-	var maxNumber int32
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			return nil
-		}
-		if err != nil {
-			log.Fatalf("error while reading client stream: %v", err)
-			return err
-		}
-		number := req.GetNumber()
-		if number > maxNumber {
-			maxNumber = number
-
-			err = stream.Send(&calculatorpb.FindMaximumResponse{
-				MaxNumber: maxNumber,
-			})
-			if err != nil {
-				log.Fatalf("error while sending data to client: %v", err)
-			}
-		}
+	if err := cfg.ServeMetrics(); err != nil {
+		log.Fatal(err)
 	}
-}
-
-func (*server) SquareRoot(ctx context.Context, req *calculatorpb.SquareRootRequest) (*calculatorpb.SquareRootResponse, error) {
-	fmt.Printf("Received SquareRoot RPC\n")
-
-	number := req.GetNumber()
-	if number < 0 {
-		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("Received a negative number: %v", number))
+	if err := cfg.ExportTraces("calculator"); err != nil {
+		log.Fatal(err)
 	}
 
-	return &calculatorpb.SquareRootResponse{
-		SqrtNumber: math.Sqrt(float64(number)),
-	}, nil
-}
-
-func main() {
-	fmt.Println("Calculator Server")
-
-	lis, err := net.Listen("tcp", "0.0.0.0:50051")
+	lis, err := net.Listen("tcp", cfg.Listen)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
@@ -134,8 +41,14 @@ func main() {
 	fmt.Println("SERVER ADDRESS: ", string(bt1))
 	fmt.Println("----------------------------------------------------------------------------------------------------------------------------------------")
 
-	s := grpc.NewServer()
-	calculatorpb.RegisterCalculatorServiceServer(s, &server{})
+	opts, err := cfg.ServerOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := grpc.NewServer(opts...)
+	calculatorservice.Register(s)
+	healthcheck.Register(s)
 
 	// Register reflection servie on gRPC server.
 	reflection.Register(s)
@@ -0,0 +1,118 @@
+// Package calculatorservice implements the CalculatorService of the course.
+package calculatorservice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorpb"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+)
+
+// Server implements calculatorpb.CalculatorServiceServer.
+type Server struct{}
+
+func (*Server) Sum(ctx context.Context, req *calculatorpb.SumRequest) (*calculatorpb.SumResponse, error) {
+	firstNum := req.GetFirstNumber()
+	secondNum := req.GetSecondNumber()
+	result := firstNum + secondNum
+	res := calculatorpb.SumResponse{
+		SumResult: result,
+	}
+
+	return &res, nil
+}
+
+func (*Server) PrimeNumberDecomposition(req *calculatorpb.PrimeNumberDecompositionRequest, stream calculatorpb.CalculatorService_PrimeNumberDecompositionServer) error {
+	number := req.GetNumber()
+	divisor := int64(2)
+
+	for number > 1 {
+		if number%divisor == 0 {
+			res := &calculatorpb.PrimeNumberDecompositionResponse{
+				PrimeFactor: divisor,
+			}
+			stream.Send(res)
+			time.Sleep(time.Second)
+			number = number / divisor
+		} else {
+			divisor++
+		}
+	}
+
+	return nil
+}
+
+func (*Server) ComputeAverage(stream calculatorpb.CalculatorService_ComputeAverageServer) error {
+	sum := 0
+	count := 0
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			// we have finished reading the client stream
+			average := float64(sum) / float64(count)
+			return stream.SendAndClose(&calculatorpb.ComputeAverageResponse{
+				Average: average,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		sum += int(req.GetNumber())
+		count++
+	}
+
+}
+
+func (*Server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumServer) error {
+	var maxNumber int32
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		number := req.GetNumber()
+		if number > maxNumber {
+			maxNumber = number
+
+			err = stream.Send(&calculatorpb.FindMaximumResponse{
+				MaxNumber: maxNumber,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (*Server) SquareRoot(ctx context.Context, req *calculatorpb.SquareRootRequest) (*calculatorpb.SquareRootResponse, error) {
+	number := req.GetNumber()
+	if number < 0 {
+		return nil, rpcerr.New(
+			codes.InvalidArgument,
+			fmt.Sprintf("Received a negative number: %v", number),
+			rpcerr.Info("NEGATIVE_NUMBER", "calculator.grpc-go-course", map[string]string{"number": fmt.Sprint(number)}),
+			rpcerr.BadRequest(rpcerr.Violation("number", "must not be negative")),
+		)
+	}
+
+	return &calculatorpb.SquareRootResponse{
+		SqrtNumber: math.Sqrt(float64(number)),
+	}, nil
+}
+
+// Register registers the CalculatorService on s.
+func Register(s *grpc.Server) {
+	calculatorpb.RegisterCalculatorServiceServer(s, &Server{})
+}
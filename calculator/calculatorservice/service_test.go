@@ -0,0 +1,44 @@
+package calculatorservice
+
+import (
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// canceledStream is the stream of a client that gave up: receiving from it
+// fails.
+type canceledStream struct{ grpc.ServerStream }
+
+func (canceledStream) RecvMsg(m interface{}) error {
+	return status.Error(codes.Canceled, "context canceled")
+}
+
+type computeAverageStream struct{ canceledStream }
+
+func (s computeAverageStream) Recv() (*calculatorpb.ComputeAverageRequest, error) {
+	return nil, s.RecvMsg(nil)
+}
+func (computeAverageStream) SendAndClose(*calculatorpb.ComputeAverageResponse) error { return nil }
+
+type findMaximumStream struct{ canceledStream }
+
+func (s findMaximumStream) Recv() (*calculatorpb.FindMaximumRequest, error) {
+	return nil, s.RecvMsg(nil)
+}
+func (findMaximumStream) Send(*calculatorpb.FindMaximumResponse) error { return nil }
+
+// A client canceling a stream ends its call, not the server.
+func TestServer_CanceledStreams(t *testing.T) {
+	s := &Server{}
+	if err := s.ComputeAverage(computeAverageStream{}); status.Code(err) != codes.Canceled {
+		t.Errorf("ComputeAverage() got error %v, want the Canceled of the stream", err)
+	}
+	if err := s.FindMaximum(findMaximumStream{}); status.Code(err) != codes.Canceled {
+		t.Errorf("FindMaximum() got error %v, want the Canceled of the stream", err)
+	}
+}
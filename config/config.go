@@ -0,0 +1,484 @@
+// Package config loads the settings shared by the gRPC servers of the course:
+// where to listen, TLS, the database, timeouts, the logging of calls, where to
+// serve metrics, where to export traces and how to run the blog services.
+//
+// Every setting has a default given by the server, which a YAML file, then
+// environment variables, then command-line flags override in that order.
+// For a server whose environment prefix is BLOG, the listen address comes from
+// the listen key of the file, BLOG_LISTEN and -listen.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/logging"
+	"github.com/mirageruler/grpc-go-course/metrics"
+	"github.com/mirageruler/grpc-go-course/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/yaml.v3"
+)
+
+// Server is the configuration of a gRPC server.
+type Server struct {
+	Listen        string   `yaml:"listen"`         // host:port
+	MetricsListen string   `yaml:"metrics_listen"` // host:port of the /metrics endpoint, none when empty
+	TLS           TLS      `yaml:"tls"`
+	Database      Database `yaml:"database"`
+	Timeouts      Timeouts `yaml:"timeouts"`
+	Log           Log      `yaml:"log"`
+	Trace         Trace    `yaml:"trace"`
+	Blog          Blog     `yaml:"blog"`
+}
+
+// TLS configures the certificate a server presents.
+type TLS struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Database locates the MongoDB collection a server keeps its data in. It is
+// left empty by servers without a database.
+type Database struct {
+	URI        string `yaml:"uri"`
+	Name       string `yaml:"name"`
+	Collection string `yaml:"collection"`
+}
+
+// Timeouts bound how long a server waits on slow operations.
+type Timeouts struct {
+	Connect  time.Duration `yaml:"connect"`  // connecting to the database
+	Shutdown time.Duration `yaml:"shutdown"` // in-flight calls to finish before the server stops
+}
+
+// Log configures the line logged for every call, see logging.Options.
+type Log struct {
+	Format   string `yaml:"format"` // logfmt or json
+	Payloads bool   `yaml:"payloads"`
+	Redact   string `yaml:"redact"` // comma-separated field names
+}
+
+// Options returns the logging.Options of l.
+func (l Log) Options() logging.Options {
+	var redact []string
+	for _, name := range strings.Split(l.Redact, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			redact = append(redact, name)
+		}
+	}
+	return logging.Options{Format: l.Format, Payloads: l.Payloads, Redact: redact}
+}
+
+// Trace configures where the spans of the calls a server handles go, see
+// tracing.NewExporter.
+type Trace struct {
+	Exporter string `yaml:"exporter"` // none, stdout or file
+	File     string `yaml:"file"`     // of the file exporter
+}
+
+// Blog configures the blog services. It is left empty by servers without
+// them.
+type Blog struct {
+	Store          string        `yaml:"store"`           // mongo or memory
+	TokensFile     string        `yaml:"tokens_file"`     // see auth.LoadTokens, no tokens when empty
+	TrashRetention time.Duration `yaml:"trash_retention"` // how long deleted blogs stay in the trash
+	PurgeInterval  time.Duration `yaml:"purge_interval"`  // how often the trash is checked for blogs to purge
+	HealthInterval time.Duration `yaml:"health_interval"` // how often the blog store is pinged for the health service
+}
+
+// Defaults returns the configuration the servers start from: listening on
+// port 50051 of every interface without TLS, and with the course's self-signed
+// certificate once TLS is enabled. Calls are logged as logfmt, without their
+// messages, and their spans are written to the standard output.
+func Defaults() Server {
+	return Server{
+		Listen: "0.0.0.0:50051",
+		TLS: TLS{
+			CertFile: "ssl/server.crt",
+			KeyFile:  "ssl/server.pem",
+		},
+		Timeouts: Timeouts{
+			Connect:  20 * time.Second,
+			Shutdown: 10 * time.Second,
+		},
+		Log:   Log{Format: "logfmt"},
+		Trace: Trace{Exporter: "stdout"},
+	}
+}
+
+// Validate reports every problem with c at once.
+func (c *Server) Validate() error {
+	var problems []string
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		problems = append(problems, fmt.Sprintf("listen: %v", err))
+	}
+	if c.MetricsListen != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsListen); err != nil {
+			problems = append(problems, fmt.Sprintf("metrics_listen: %v", err))
+		}
+	}
+	if c.TLS.Enabled {
+		files := []struct{ name, path string }{
+			{"tls.cert_file", c.TLS.CertFile},
+			{"tls.key_file", c.TLS.KeyFile},
+		}
+		for _, file := range files {
+			if file.path == "" {
+				problems = append(problems, file.name+" is required with TLS enabled")
+			} else if _, err := os.Stat(file.path); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", file.name, err))
+			}
+		}
+	}
+	if c.Database.URI != "" {
+		if !strings.HasPrefix(c.Database.URI, "mongodb://") && !strings.HasPrefix(c.Database.URI, "mongodb+srv://") {
+			problems = append(problems, "database.uri must start with mongodb:// or mongodb+srv://")
+		}
+		if c.Database.Name == "" {
+			problems = append(problems, "database.name is required with a database.uri")
+		}
+		if c.Database.Collection == "" {
+			problems = append(problems, "database.collection is required with a database.uri")
+		}
+	}
+	if c.Timeouts.Connect <= 0 {
+		problems = append(problems, "timeouts.connect must be positive")
+	}
+	if c.Timeouts.Shutdown <= 0 {
+		problems = append(problems, "timeouts.shutdown must be positive")
+	}
+	if _, err := logging.New(io.Discard, c.Log.Options()); err != nil {
+		problems = append(problems, fmt.Sprintf("log.format: %v", err))
+	}
+	if !contains(tracing.Exporters, c.Trace.Exporter) {
+		problems = append(problems, fmt.Sprintf("trace.exporter must be one of %v", tracing.Exporters))
+	} else if c.Trace.Exporter == "file" && c.Trace.File == "" {
+		problems = append(problems, "trace.file is required with the file exporter")
+	}
+
+	if c.Blog.Store != "" {
+		if c.Blog.Store != "mongo" && c.Blog.Store != "memory" {
+			problems = append(problems, "blog.store must be mongo or memory")
+		}
+		durations := []struct {
+			name string
+			d    time.Duration
+		}{
+			{"blog.trash_retention", c.Blog.TrashRetention},
+			{"blog.purge_interval", c.Blog.PurgeInterval},
+			{"blog.health_interval", c.Blog.HealthInterval},
+		}
+		for _, d := range durations {
+			if d.d <= 0 {
+				problems = append(problems, d.name+" must be positive")
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerOptions returns the options that make a gRPC server follow c: its
+// TLS credentials, and interceptors recording a span of every call with
+// tracing.DefaultTracer, logging it to stderr and recording it in
+// metrics.DefaultRegistry. Interceptors chained after them see the calls they
+// pass on, so the status they trace, log and record is the final one.
+func (c *Server) ServerOptions() ([]grpc.ServerOption, error) {
+	logger, err := logging.New(os.Stderr, c.Log.Options())
+	if err != nil {
+		return nil, err
+	}
+	serverMetrics := metrics.NewServerMetrics(metrics.DefaultRegistry)
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			tracing.UnaryServerInterceptor(tracing.DefaultTracer),
+			logging.UnaryServerInterceptor(logger),
+			metrics.UnaryServerInterceptor(serverMetrics),
+		),
+		grpc.ChainStreamInterceptor(
+			tracing.StreamServerInterceptor(tracing.DefaultTracer),
+			logging.StreamServerInterceptor(logger),
+			metrics.StreamServerInterceptor(serverMetrics),
+		),
+	}
+
+	if c.TLS.Enabled {
+		creds, err := credentials.NewServerTLSFromFile(c.TLS.CertFile, c.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading certificates: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	return opts, nil
+}
+
+// ServeMetrics serves metrics.DefaultRegistry at /metrics on MetricsListen in
+// the background, if it is set, for as long as the process runs.
+func (c *Server) ServeMetrics() error {
+	if c.MetricsListen == "" {
+		return nil
+	}
+	lis, err := net.Listen("tcp", c.MetricsListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.DefaultRegistry.Handler())
+	go func() {
+		fmt.Printf("Serving metrics on http://%v/metrics...\n", lis.Addr())
+		if err := http.Serve(lis, mux); err != nil {
+			log.Printf("failed to serve metrics: %v", err)
+		}
+	}()
+	return nil
+}
+
+// ExportTraces makes tracing.DefaultTracer export the spans of service with
+// the exporter of Trace.
+func (c *Server) ExportTraces(service string) error {
+	e, err := tracing.NewExporter(c.Trace.Exporter, c.Trace.File)
+	if err != nil {
+		return fmt.Errorf("failed to open the trace exporter: %v", err)
+	}
+	tracing.DefaultTracer.Configure(service, e)
+	return nil
+}
+
+// GracefulStop stops s once the calls in flight finish, cancelling the ones
+// still running after Timeouts.Shutdown.
+func (c *Server) GracefulStop(s *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(c.Timeouts.Shutdown):
+		fmt.Println("Cancelling the calls still in flight...")
+		s.Stop()
+	}
+}
+
+// setting is a configuration value that can be overridden by an environment
+// variable and a flag.
+type setting struct {
+	name  string // of the flag, the environment variable is derived from it
+	usage string
+	field func(c *Server) interface{}
+}
+
+var settings = []setting{
+	{"listen", "`host:port` to listen on", func(c *Server) interface{} { return &c.Listen }},
+	{"metrics-listen", "`host:port` to serve Prometheus metrics on at /metrics, none when empty", func(c *Server) interface{} { return &c.MetricsListen }},
+	{"tls", "serve with TLS", func(c *Server) interface{} { return &c.TLS.Enabled }},
+	{"tls-cert-file", "certificate `file` to serve with TLS", func(c *Server) interface{} { return &c.TLS.CertFile }},
+	{"tls-key-file", "private key `file` of the TLS certificate", func(c *Server) interface{} { return &c.TLS.KeyFile }},
+	{"db-uri", "MongoDB connection `URI`", func(c *Server) interface{} { return &c.Database.URI }},
+	{"db-name", "MongoDB `database`", func(c *Server) interface{} { return &c.Database.Name }},
+	{"db-collection", "MongoDB `collection`", func(c *Server) interface{} { return &c.Database.Collection }},
+	{"db-connect-timeout", "how long to wait for MongoDB to connect, a `duration`", func(c *Server) interface{} { return &c.Timeouts.Connect }},
+	{"shutdown-timeout", "how long to wait for in-flight calls when stopping, a `duration`", func(c *Server) interface{} { return &c.Timeouts.Shutdown }},
+	{"log-format", "`format` of the line logged for every call: logfmt or json", func(c *Server) interface{} { return &c.Log.Format }},
+	{"log-payloads", "also log the request and response messages of unary calls", func(c *Server) interface{} { return &c.Log.Payloads }},
+	{"log-redact", "comma-separated `fields` whose values logged messages hide", func(c *Server) interface{} { return &c.Log.Redact }},
+	{"trace-exporter", "`exporter` of the spans of calls: none, stdout or file", func(c *Server) interface{} { return &c.Trace.Exporter }},
+	{"trace-file", "`file` the file exporter appends spans to as JSON lines", func(c *Server) interface{} { return &c.Trace.File }},
+}
+
+// blogSettings are the settings of the blog services, only registered for
+// servers whose defaults have them.
+var blogSettings = []setting{
+	{"store", "blog storage `backend`: mongo or memory", func(c *Server) interface{} { return &c.Blog.Store }},
+	{"tokens", "`file` of bearer tokens with the author and roles each identifies", func(c *Server) interface{} { return &c.Blog.TokensFile }},
+	{"trash-retention", "how long deleted blogs stay in the trash before they are purged, a `duration`", func(c *Server) interface{} { return &c.Blog.TrashRetention }},
+	{"purge-interval", "how often the trash is checked for blogs to purge, a `duration`", func(c *Server) interface{} { return &c.Blog.PurgeInterval }},
+	{"health-interval", "how often the blog store is pinged, the blog services stop serving while it fails, a `duration`", func(c *Server) interface{} { return &c.Blog.HealthInterval }},
+}
+
+// set parses s into the field, which is one of the pointers settings return.
+func set(field interface{}, s string) error {
+	switch p := field.(type) {
+	case *string:
+		*p = s
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*p = d
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+	return nil
+}
+
+// Loader loads a Server configuration from the sources of one server.
+type Loader struct {
+	envPrefix string
+	defaults  Server
+	file      string
+	settings  []setting
+	flags     map[string]string // the values of the flags given on the command line
+
+	lookupEnv func(key string) (string, bool)
+}
+
+// Register defines the -config flag and a flag per setting on fs, and
+// returns the Loader to call once fs is parsed. envPrefix names the
+// environment variables of the server, such as BLOG for BLOG_LISTEN. The
+// settings of Blog are only defined when defaults sets them, so that servers
+// without the blog services do not offer them.
+func Register(fs *flag.FlagSet, envPrefix string, defaults Server) *Loader {
+	l := &Loader{
+		envPrefix: envPrefix,
+		defaults:  defaults,
+		settings:  settings,
+		flags:     make(map[string]string),
+		lookupEnv: os.LookupEnv,
+	}
+	if defaults.Blog != (Blog{}) {
+		l.settings = append(append([]setting{}, settings...), blogSettings...)
+	}
+
+	fs.StringVar(&l.file, "config", "", fmt.Sprintf("YAML `file` to load the settings from, also $%s_CONFIG", envPrefix))
+	for _, s := range l.settings {
+		fs.Var(&flagValue{l: l, s: s}, s.name, fmt.Sprintf("%s, also $%s", s.usage, l.envName(s)))
+	}
+
+	return l
+}
+
+func (l *Loader) envName(s setting) string {
+	return l.envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(s.name, "-", "_"))
+}
+
+// Load returns the validated configuration: the defaults overridden by the
+// config file, then by the environment, then by the flags.
+func (l *Loader) Load() (*Server, error) {
+	c := l.defaults
+
+	file := l.file
+	if file == "" {
+		file, _ = l.lookupEnv(l.envPrefix + "_CONFIG")
+	}
+	if file != "" {
+		if err := readFile(file, &c); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range l.settings {
+		if v, ok := l.lookupEnv(l.envName(s)); ok {
+			if err := set(s.field(&c), v); err != nil {
+				return nil, fmt.Errorf("invalid $%s: %v", l.envName(s), err)
+			}
+		}
+	}
+	for _, s := range l.settings {
+		if v, ok := l.flags[s.name]; ok {
+			// checked by flagValue.Set already
+			set(s.field(&c), v)
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// readFile overrides the settings of c that the YAML file at path has,
+// rejecting unknown keys so that typos do not go unnoticed.
+func readFile(path string, c *Server) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("cannot read %s: %v", path, err)
+	}
+	return nil
+}
+
+// flagValue is the flag.Value of a setting. It only records the value given
+// on the command line, so that the flag overrides the other sources.
+type flagValue struct {
+	l *Loader
+	s setting
+}
+
+func (v *flagValue) String() string {
+	if v.l == nil {
+		return ""
+	}
+	c := v.l.defaults
+	value := deref(v.s.field(&c))
+	// like the flags of the flag package, zero defaults are not shown
+	if value == "" || value == false || value == time.Duration(0) {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func (v *flagValue) Set(s string) error {
+	if err := set(v.s.field(&Server{}), s); err != nil {
+		return err
+	}
+	v.l.flags[v.s.name] = s
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	_, ok := v.s.field(&Server{}).(*bool)
+	return ok
+}
+
+// deref returns the value a field pointer returned by settings points to.
+func deref(field interface{}) interface{} {
+	switch p := field.(type) {
+	case *string:
+		return *p
+	case *bool:
+		return *p
+	case *time.Duration:
+		return *p
+	}
+	return field
+}
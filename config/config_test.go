@@ -0,0 +1,160 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestLoader registers the flags on a new FlagSet, parses args and reads
+// the environment from env.
+func newTestLoader(t *testing.T, env map[string]string, args ...string) *Loader {
+	return newDefaultsTestLoader(t, Defaults(), env, args...)
+}
+
+// blogDefaults are the defaults of a server with the blog services.
+func blogDefaults() Server {
+	c := Defaults()
+	c.Blog = Blog{Store: "mongo", TrashRetention: 24 * time.Hour, PurgeInterval: time.Hour, HealthInterval: 10 * time.Second}
+	return c
+}
+
+// newDefaultsTestLoader is newTestLoader for a server with the given defaults.
+func newDefaultsTestLoader(t *testing.T, defaults Server, env map[string]string, args ...string) *Loader {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l := Register(fs, "TEST", defaults)
+	l.lookupEnv = func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse(%q) had unexpected error: %v", args, err)
+	}
+	return l
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() had unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestLoader_Defaults(t *testing.T) {
+	c, err := newTestLoader(t, nil).Load()
+	if err != nil {
+		t.Fatalf("Load() had unexpected error: %v", err)
+	}
+	if *c != Defaults() {
+		t.Errorf("Load() got %+v, want the defaults %+v", *c, Defaults())
+	}
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	path := writeFile(t, "listen: file:1\ndatabase:\n  uri: mongodb://file\n  name: filedb\n  collection: blog\ntimeouts:\n  connect: 5s\nblog:\n  store: memory\n  tokens_file: file.txt\n  trash_retention: 1h\n  purge_interval: 1m\n  health_interval: 1s\n")
+	env := map[string]string{
+		"TEST_CONFIG":             path,
+		"TEST_LISTEN":             "env:2",
+		"TEST_DB_NAME":            "envdb",
+		"TEST_DB_CONNECT_TIMEOUT": "7s",
+		"TEST_TOKENS":             "env.txt",
+		"TEST_PURGE_INTERVAL":     "2m",
+	}
+
+	c, err := newDefaultsTestLoader(t, blogDefaults(), env, "-listen=flag:3", "-tokens=flag.txt", "-health-interval=3s").Load()
+	if err != nil {
+		t.Fatalf("Load() had unexpected error: %v", err)
+	}
+	want := blogDefaults()
+	want.Listen = "flag:3"
+	want.Database = Database{URI: "mongodb://file", Name: "envdb", Collection: "blog"}
+	want.Timeouts.Connect = 7 * time.Second
+	want.Blog = Blog{Store: "memory", TokensFile: "flag.txt", TrashRetention: time.Hour, PurgeInterval: 2 * time.Minute, HealthInterval: 3 * time.Second}
+	if *c != want {
+		t.Errorf("Load() got %+v, want %+v", *c, want)
+	}
+}
+
+func TestLoader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{"unknown key", map[string]string{"TEST_CONFIG": writeFile(t, "listn: :1\n")}, nil, "field listn not found"},
+		{"bad env", map[string]string{"TEST_TLS": "maybe"}, nil, "invalid $TEST_TLS"},
+		{"bad listen", nil, []string{"-listen=nowhere"}, "listen: address nowhere: missing port in address"},
+		{"missing cert", nil, []string{"-tls", "-tls-cert-file=", "-tls-key-file=/does/not/exist"}, "tls.cert_file is required with TLS enabled; tls.key_file:"},
+		{"incomplete database", map[string]string{"TEST_DB_URI": "postgres://db"}, nil, "database.uri must start with mongodb:// or mongodb+srv://; database.name is required"},
+		{"zero timeout", nil, []string{"-shutdown-timeout=0s"}, "timeouts.shutdown must be positive"},
+		{"unknown log format", map[string]string{"TEST_LOG_FORMAT": "xml"}, nil, `log.format: unknown log format "xml"`},
+		{"unknown trace exporter", nil, []string{"-trace-exporter=zipkin"}, "trace.exporter must be one of [none stdout file]"},
+		{"missing trace file", map[string]string{"TEST_TRACE_EXPORTER": "file"}, nil, "trace.file is required with the file exporter"},
+		{"unknown store", nil, []string{"-store=postgres"}, "blog.store must be mongo or memory"},
+		{"zero purge interval", map[string]string{"TEST_CONFIG": writeFile(t, "blog:\n  store: memory\n  trash_retention: 1h\n  purge_interval: 0s\n  health_interval: 1s\n")}, nil, "blog.purge_interval must be positive"},
+		{"bad trash retention", map[string]string{"TEST_TRASH_RETENTION": "a month"}, nil, "invalid $TEST_TRASH_RETENTION"},
+	}
+
+	for _, tt := range tests {
+		_, err := newDefaultsTestLoader(t, blogDefaults(), tt.env, tt.args...).Load()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%s) got error %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRegister_BadFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	Register(fs, "TEST", Defaults())
+	if err := fs.Parse([]string{"-db-connect-timeout=soon"}); err == nil {
+		t.Errorf("Parse(-db-connect-timeout=soon) got no error")
+	}
+}
+
+func TestRegister_BlogSettings(t *testing.T) {
+	// a server without the blog services neither offers nor reads them
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	Register(fs, "TEST", Defaults())
+	for _, name := range []string{"store", "tokens", "trash-retention", "purge-interval", "health-interval"} {
+		if fs.Lookup(name) != nil {
+			t.Errorf("Register() without blog defaults defined -%s", name)
+		}
+	}
+	c, err := newTestLoader(t, map[string]string{"TEST_STORE": "postgres"}).Load()
+	if err != nil || c.Blog != (Blog{}) {
+		t.Errorf("Load() without blog defaults got blog %+v, %v, want $TEST_STORE ignored", c.Blog, err)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	Register(fs, "TEST", blogDefaults())
+	if f := fs.Lookup("trash-retention"); f == nil || f.DefValue != "24h0m0s" {
+		t.Errorf("Register() with blog defaults got -trash-retention %+v, want it defaulting to 24h", f)
+	}
+}
+
+func TestLog_Options(t *testing.T) {
+	l := Log{Format: "json", Payloads: true, Redact: " content, ,title"}
+	got := l.Options()
+	if got.Format != "json" || !got.Payloads || strings.Join(got.Redact, "|") != "content|title" {
+		t.Errorf("Options() got %+v, want json payloads redacting [content title]", got)
+	}
+}
+
+func TestExampleFile(t *testing.T) {
+	c, err := newTestLoader(t, nil, "-config=example.yaml").Load()
+	if err != nil {
+		t.Fatalf("Load(example.yaml) had unexpected error: %v", err)
+	}
+	if c.Database.Collection != "blog" {
+		t.Errorf("Load(example.yaml) got database %+v, want the blog collection", c.Database)
+	}
+	if c.Blog.Store != "mongo" || c.Blog.TrashRetention != 30*24*time.Hour {
+		t.Errorf("Load(example.yaml) got blog %+v, want mongo keeping the trash for 30 days", c.Blog)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/blogservice"
+	"github.com/mirageruler/grpc-go-course/blog/validate"
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorpb"
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorservice"
+	"github.com/mirageruler/grpc-go-course/config"
+	"github.com/mirageruler/grpc-go-course/greet/greetservice"
+	"github.com/mirageruler/grpc-go-course/healthcheck"
+	"github.com/mirageruler/grpc-go-course/metrics"
+	"github.com/mirageruler/grpc-go-course/rest"
+	"github.com/mirageruler/grpc-go-course/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+)
+
+// The gateway serves every service of the course on one port, so that they
+// can run together in a single process.
+func main() {
+	// if we crash the go code, we  get the file name and the line number
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	enableGreet := flag.Bool("greet", true, "serve the GreetService")
+	enableCalculator := flag.Bool("calculator", true, "serve the CalculatorService")
+	enableBlog := flag.Bool("blog", true, "serve the BlogService and the CommentService")
+	restListen := flag.String("rest-listen", "0.0.0.0:8080", "`host:port` to serve the BlogService and the CalculatorService as REST/JSON on, empty to only serve gRPC")
+	defaults := config.Defaults()
+	defaults.Database = blogservice.DefaultDatabase()
+	defaults.Blog = blogservice.DefaultBlog()
+	loader := config.Register(flag.CommandLine, "GATEWAY", defaults)
+	flag.Parse()
+
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.ServeMetrics(); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.ExportTraces("gateway"); err != nil {
+		log.Fatal(err)
+	}
+	if !*enableGreet && !*enableCalculator && !*enableBlog {
+		log.Fatal("no service to serve, enable at least one of -greet, -calculator and -blog")
+	}
+
+	fmt.Println("Gateway Server")
+
+	// calls without a token stay anonymous, and requests without rules pass
+	// validation, so the blog interceptors are harmless to the other services
+	tokens := auth.TokenTable{}
+	if *enableBlog {
+		if tokens, err = blogservice.Tokens(cfg.Blog); err != nil {
+			log.Fatalf("failed to load tokens: %v", err)
+		}
+	}
+
+	opts, err := cfg.ServerOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(
+			auth.UnaryServerInterceptor(tokens),
+			validate.UnaryServerInterceptor(blogservice.RequestRules),
+		),
+		grpc.ChainStreamInterceptor(
+			auth.StreamServerInterceptor(tokens),
+			validate.StreamServerInterceptor(blogservice.RequestRules),
+		),
+	)
+	s := grpc.NewServer(opts...)
+
+	if *enableGreet {
+		greetservice.Register(s)
+	}
+	if *enableCalculator {
+		calculatorservice.Register(s)
+	}
+
+	var stores *blogservice.Stores
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	if *enableBlog {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Connect)
+		stores, err = blogservice.OpenStores(ctx, cfg.Blog, cfg.Database)
+		cancel()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		blogServer := blogservice.NewServer(stores.Blogs, stores.History, stores.Comments)
+		blogServer.Register(s)
+		go blogServer.RunPurger(backgroundCtx, cfg.Blog.PurgeInterval, cfg.Blog.TrashRetention)
+	}
+
+	// every service registered so far is ready, and so is the server as a
+	// whole, which keeps serving the other services while the blog store fails
+	healthServer := healthcheck.Register(s)
+	if *enableBlog {
+		go healthcheck.Watch(backgroundCtx, healthServer, "blog store", stores.Ping, cfg.Blog.HealthInterval, blogservice.Services...)
+	}
+
+	// Register reflection service on gRPC server.
+	reflection.Register(s)
+
+	lis, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	go func() {
+		fmt.Printf("Serving %d services on %v...\n", len(s.GetServiceInfo()), lis.Addr())
+		if err := s.Serve(lis); err != nil {
+			log.Fatalf("failed to serve: %v", err)
+		}
+	}()
+
+	var restServer *http.Server
+	var conn *grpc.ClientConn
+	if *restListen != "" && (*enableBlog || *enableCalculator) {
+		// the REST routes call the gRPC server, so they go through its
+		// interceptors like any other client
+		conn, err = dialSelf(cfg, lis.Addr())
+		if err != nil {
+			log.Fatalf("failed to connect the REST gateway: %v", err)
+		}
+		var blogClient blogpb.BlogServiceClient
+		if *enableBlog {
+			blogClient = blogpb.NewBlogServiceClient(conn)
+		}
+		var calculatorClient calculatorpb.CalculatorServiceClient
+		if *enableCalculator {
+			calculatorClient = calculatorpb.NewCalculatorServiceClient(conn)
+		}
+		restServer = &http.Server{Addr: *restListen, Handler: rest.New(blogClient, calculatorClient)}
+
+		go func() {
+			fmt.Printf("Serving REST on %v...\n", *restListen)
+			if err := restServer.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatalf("failed to serve REST: %v", err)
+			}
+		}()
+	}
+
+	// Wait for Control C to exit
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+
+	// Block until a signal is received
+	<-ch
+	// health checks fail from now on, so load balancers stop sending calls
+	healthServer.Shutdown()
+	stopBackground()
+	if restServer != nil {
+		fmt.Println("Stopping the REST gateway...")
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown)
+		restServer.Shutdown(ctx)
+		cancel()
+		conn.Close()
+	}
+	fmt.Println("Stopping the server...")
+	cfg.GracefulStop(s)
+	fmt.Println("Closing the listener...")
+	lis.Close()
+	if stores != nil {
+		stores.Close(context.Background())
+	}
+	tracing.DefaultTracer.Close()
+	fmt.Println("End of Program")
+}
+
+// dialSelf connects to the gRPC server listening on addr, through the
+// loopback interface when it listens on every interface, tracing the calls
+// with tracing.DefaultTracer and recording them in metrics.DefaultRegistry.
+func dialSelf(cfg *config.Server, addr net.Addr) (*grpc.ClientConn, error) {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+
+	clientMetrics := metrics.NewClientMetrics(metrics.DefaultRegistry)
+	opts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(
+			tracing.UnaryClientInterceptor(tracing.DefaultTracer),
+			metrics.UnaryClientInterceptor(clientMetrics),
+		),
+		grpc.WithChainStreamInterceptor(
+			tracing.StreamClientInterceptor(tracing.DefaultTracer),
+			metrics.StreamClientInterceptor(clientMetrics),
+		),
+	}
+	if cfg.TLS.Enabled {
+		// the server's own certificate is the only one to trust, and it is
+		// issued to localhost
+		creds, err := credentials.NewClientTLSFromFile(cfg.TLS.CertFile, "localhost")
+		if err != nil {
+			return nil, fmt.Errorf("failed loading certificates: %v", err)
+		}
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	return grpc.Dial(net.JoinHostPort(host, port), opts...)
+}
@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"time"
 
 	"github.com/mirageruler/grpc-go-course/greet/greetpb"
+	"github.com/mirageruler/grpc-go-course/metrics"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+	"github.com/mirageruler/grpc-go-course/tracing"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -33,7 +37,19 @@ func main() {
 		opts = grpc.WithTransportCredentials(creds)
 	}
 
-	conn, err := grpc.Dial("localhost:50051", opts)
+	// every call starts a trace, which the server joins
+	tracing.DefaultTracer.Configure("greet_client", tracing.NewJSONExporter(os.Stdout))
+	clientMetrics := metrics.NewClientMetrics(metrics.DefaultRegistry)
+	conn, err := grpc.Dial("localhost:50051", opts,
+		grpc.WithChainUnaryInterceptor(
+			tracing.UnaryClientInterceptor(tracing.DefaultTracer),
+			metrics.UnaryClientInterceptor(clientMetrics),
+		),
+		grpc.WithChainStreamInterceptor(
+			tracing.StreamClientInterceptor(tracing.DefaultTracer),
+			metrics.StreamClientInterceptor(clientMetrics),
+		),
+	)
 	if err != nil {
 		log.Fatalf("could not connect: %v", err)
 	}
@@ -235,7 +251,7 @@ func doUnaryWithDeadline(c greetpb.GreetServiceClient, timeout time.Duration) {
 			if errStatus.Code() == codes.DeadlineExceeded {
 				fmt.Println("Timeout was hit! Deadline was exceeded")
 			} else {
-				fmt.Printf("Unexpected error: %v\n", errStatus)
+				fmt.Printf("Unexpected error: %v\n", rpcerr.Describe(err))
 			}
 		} else {
 			log.Fatalf("error while calling GreetWithDeadline RPC: %v\n", err)
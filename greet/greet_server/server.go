@@ -1,121 +1,39 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net"
-	"strconv"
-	"time"
 
-	"github.com/mirageruler/grpc-go-course/greet/greetpb"
+	"github.com/mirageruler/grpc-go-course/config"
+	"github.com/mirageruler/grpc-go-course/greet/greetservice"
+	"github.com/mirageruler/grpc-go-course/healthcheck"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/status"
 )
 
-type server struct{}
-
-func (*server) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.GreetResponse, error) {
-	fmt.Printf("Greet func was invoked with %v\n", req)
-
-	firstName := req.GetGreeting().GetFirstName()
-	result := "Hello " + firstName
-	res := greetpb.GreetResponse{
-		Result: result,
-	}
-
-	return &res, nil
-}
-
-func (*server) GreetManyTimes(req *greetpb.GreetManyTimesRequest, stream greetpb.GreetService_GreetManyTimesServer) error {
-	fmt.Printf("GreetManyTimes func was invoked with %v\n", req)
-	firstName := req.GetGreeting().GetFirstName()
-	for i := 0; i < 10; i++ {
-		result := "Hello" + firstName + " number " + strconv.Itoa(i)
-		res := &greetpb.GreetManyTimesResponse{
-			Result: result,
-		}
-		stream.Send(res)
-		time.Sleep(time.Second)
-	}
-	return nil
-}
-
-func (*server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
-	fmt.Printf("LongGreet func was invoked with a streaming request\n")
-
-	result := ""
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			// we have finished reading the client stream
-			return stream.SendAndClose(&greetpb.LongGreetResponse{
-				Result: result,
-			})
-		}
-		if err != nil {
-			log.Fatalf("Error while reading client stream: %v", err)
-			return err
-		}
-
-		firstName := req.GetGreeting().GetFirstName()
-		result += "Hello " + firstName + "! "
-	}
-}
-
-func (*server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer) error {
-	fmt.Printf("GreetEveryone func was invoked with a streaming request\n")
-
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			return nil
-		}
-		if err != nil {
-			log.Fatalf("error while reading client stream: %v", err)
-			return err
-		}
-		firstName := req.GetGreeting().GetFirstName()
-		result := "Hello " + firstName + "! "
+func main() {
+	fmt.Println("Greet Server")
 
-		err = stream.Send(&greetpb.GreetEveryoneResponse{
-			Result: result,
-		})
-		if err != nil {
-			log.Fatalf("error while sending data to client: %v", err)
-		}
+	// the greet demo runs with TLS unless told otherwise
+	defaults := config.Defaults()
+	defaults.TLS.Enabled = true
+	loader := config.Register(flag.CommandLine, "GREET", defaults)
+	flag.Parse()
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
 	}
-}
-
-func (*server) GreetWithDeadline(ctx context.Context, req *greetpb.GreetWithDeadlineRequest) (*greetpb.GreetWithDeadlineResponse, error) {
-	fmt.Printf("GreetWithDeadline func was invoked with %v\n", req)
-
-	for i := 0; i < 3; i++ {
-		if ctx.Err() == context.Canceled {
-			// the client canceled the request
-			fmt.Println("The client canceled the request!")
-			return nil, status.Error(codes.DeadlineExceeded, "the client canceled the request")
-		}
-		time.Sleep(1 * time.Second)
+	if err := cfg.ServeMetrics(); err != nil {
+		log.Fatal(err)
 	}
-	firstName := req.GetGreeting().GetFirstName()
-	result := "Hello " + firstName
-	res := greetpb.GreetWithDeadlineResponse{
-		Result: result,
+	if err := cfg.ExportTraces("greet"); err != nil {
+		log.Fatal(err)
 	}
 
-	return &res, nil
-}
-
-func main() {
-	fmt.Println("Greet Server")
-
-	lis, err := net.Listen("tcp", "0.0.0.0:50051")
+	lis, err := net.Listen("tcp", cfg.Listen)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
@@ -125,22 +43,14 @@ func main() {
 	fmt.Println("ADDRESS: ", string(bt1))
 	fmt.Println("----------------------------------------------------------------------------------------------------------------------------------------")
 
-	tls := true // TLS flag.
-	opts := []grpc.ServerOption{}
-	if tls {
-		certFile := "ssl/server.crt"
-		keyFile := "ssl/server.pem"
-		creds, sslErr := credentials.NewServerTLSFromFile(certFile, keyFile)
-		if sslErr != nil {
-			log.Fatalf("failed loading certificates: %v", sslErr)
-			return
-		}
-
-		opts = append(opts, grpc.Creds(creds))
+	opts, err := cfg.ServerOptions()
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	s := grpc.NewServer(opts...)
-	greetpb.RegisterGreetServiceServer(s, &server{})
+	greetservice.Register(s)
+	healthcheck.Register(s)
 
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
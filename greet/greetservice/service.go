@@ -0,0 +1,107 @@
+// Package greetservice implements the GreetService of the course.
+package greetservice
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/greet/greetpb"
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Server implements greetpb.GreetServiceServer.
+type Server struct{}
+
+func (*Server) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.GreetResponse, error) {
+	firstName := req.GetGreeting().GetFirstName()
+	result := "Hello " + firstName
+	res := greetpb.GreetResponse{
+		Result: result,
+	}
+
+	return &res, nil
+}
+
+func (*Server) GreetManyTimes(req *greetpb.GreetManyTimesRequest, stream greetpb.GreetService_GreetManyTimesServer) error {
+	firstName := req.GetGreeting().GetFirstName()
+	for i := 0; i < 10; i++ {
+		result := "Hello" + firstName + " number " + strconv.Itoa(i)
+		res := &greetpb.GreetManyTimesResponse{
+			Result: result,
+		}
+		stream.Send(res)
+		time.Sleep(time.Second)
+	}
+	return nil
+}
+
+func (*Server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
+	result := ""
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			// we have finished reading the client stream
+			return stream.SendAndClose(&greetpb.LongGreetResponse{
+				Result: result,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		firstName := req.GetGreeting().GetFirstName()
+		result += "Hello " + firstName + "! "
+	}
+}
+
+func (*Server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		firstName := req.GetGreeting().GetFirstName()
+		result := "Hello " + firstName + "! "
+
+		err = stream.Send(&greetpb.GreetEveryoneResponse{
+			Result: result,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (*Server) GreetWithDeadline(ctx context.Context, req *greetpb.GreetWithDeadlineRequest) (*greetpb.GreetWithDeadlineResponse, error) {
+	for i := 0; i < 3; i++ {
+		if ctx.Err() == context.Canceled {
+			// the client canceled the request
+			return nil, rpcerr.New(
+				codes.DeadlineExceeded,
+				"the client canceled the request",
+				rpcerr.Info("CLIENT_CANCELED", "greet.grpc-go-course", nil),
+			)
+		}
+		time.Sleep(1 * time.Second)
+	}
+	firstName := req.GetGreeting().GetFirstName()
+	result := "Hello " + firstName
+	res := greetpb.GreetWithDeadlineResponse{
+		Result: result,
+	}
+
+	return &res, nil
+}
+
+// Register registers the GreetService on s.
+func Register(s *grpc.Server) {
+	greetpb.RegisterGreetServiceServer(s, &Server{})
+}
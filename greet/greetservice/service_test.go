@@ -0,0 +1,42 @@
+package greetservice
+
+import (
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/greet/greetpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// canceledStream is the stream of a client that gave up: receiving from it
+// fails.
+type canceledStream struct{ grpc.ServerStream }
+
+func (canceledStream) RecvMsg(m interface{}) error {
+	return status.Error(codes.Canceled, "context canceled")
+}
+
+type longGreetStream struct{ canceledStream }
+
+func (s longGreetStream) Recv() (*greetpb.LongGreetRequest, error)    { return nil, s.RecvMsg(nil) }
+func (longGreetStream) SendAndClose(*greetpb.LongGreetResponse) error { return nil }
+
+type greetEveryoneStream struct{ canceledStream }
+
+func (s greetEveryoneStream) Recv() (*greetpb.GreetEveryoneRequest, error) {
+	return nil, s.RecvMsg(nil)
+}
+func (greetEveryoneStream) Send(*greetpb.GreetEveryoneResponse) error { return nil }
+
+// A client canceling a stream ends its call, not the server.
+func TestServer_CanceledStreams(t *testing.T) {
+	s := &Server{}
+	if err := s.LongGreet(longGreetStream{}); status.Code(err) != codes.Canceled {
+		t.Errorf("LongGreet() got error %v, want the Canceled of the stream", err)
+	}
+	if err := s.GreetEveryone(greetEveryoneStream{}); status.Code(err) != codes.Canceled {
+		t.Errorf("GreetEveryone() got error %v, want the Canceled of the stream", err)
+	}
+}
@@ -0,0 +1,68 @@
+// Package healthcheck serves the standard gRPC health service,
+// grpc.health.v1.Health, and keeps the status of each service in step with
+// the dependencies it needs, such as a database.
+package healthcheck
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Register registers a health service on s and returns it. Every service
+// registered on s so far is SERVING, and so is the server as a whole, under
+// the empty service name.
+func Register(s *grpc.Server) *health.Server {
+	hs := health.NewServer()
+	for name := range s.GetServiceInfo() {
+		hs.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(s, hs)
+	return hs
+}
+
+// Watch runs check every interval until ctx is done, and sets the given
+// services SERVING while it succeeds and NOT_SERVING while it fails. Each
+// check is given one interval to finish. Changes are logged with the name of
+// the dependency check looks at.
+//
+// Once hs is shut down, its status no longer changes.
+func Watch(ctx context.Context, hs *health.Server, dependency string, check func(ctx context.Context) error, interval time.Duration, services ...string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		err := check(checkCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil && healthy {
+			log.Printf("%s check failed, %v stop serving: %v", dependency, services, err)
+			setStatus(hs, healthpb.HealthCheckResponse_NOT_SERVING, services)
+		} else if err == nil && !healthy {
+			log.Printf("%s check succeeded, %v serve again", dependency, services)
+			setStatus(hs, healthpb.HealthCheckResponse_SERVING, services)
+		}
+		healthy = err == nil
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func setStatus(hs *health.Server, status healthpb.HealthCheckResponse_ServingStatus, services []string) {
+	for _, service := range services {
+		hs.SetServingStatus(service, status)
+	}
+}
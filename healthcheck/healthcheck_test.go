@@ -0,0 +1,92 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/greet/greetservice"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func status(t *testing.T, hs *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	res, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Check(%q) had unexpected error: %v", service, err)
+	}
+	return res.GetStatus()
+}
+
+// waitFor waits for service to have the status want.
+func waitFor(t *testing.T, hs *health.Server, service string, want healthpb.HealthCheckResponse_ServingStatus) {
+	deadline := time.Now().Add(time.Second)
+	for status(t, hs, service) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Check(%q) got %v, want %v", service, status(t, hs, service), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	s := grpc.NewServer()
+	greetservice.Register(s)
+	hs := Register(s)
+
+	for _, service := range []string{"", "greet.GreetService"} {
+		if got := status(t, hs, service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Check(%q) got %v, want SERVING", service, got)
+		}
+	}
+	if _, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "blog.BlogService"}); err == nil {
+		t.Errorf("Check(blog.BlogService) got no error, want NotFound for a service that is not registered")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("blog.BlogService", healthpb.HealthCheckResponse_SERVING)
+
+	var down atomic.Value
+	down.Store(false)
+	check := func(ctx context.Context) error {
+		if down.Load().(bool) {
+			return errors.New("no reachable servers")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, hs, "test store", check, time.Millisecond, "blog.BlogService", "")
+		close(done)
+	}()
+
+	down.Store(true)
+	waitFor(t, hs, "blog.BlogService", healthpb.HealthCheckResponse_NOT_SERVING)
+	waitFor(t, hs, "", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	down.Store(false)
+	waitFor(t, hs, "blog.BlogService", healthpb.HealthCheckResponse_SERVING)
+	waitFor(t, hs, "", healthpb.HealthCheckResponse_SERVING)
+
+	// a shut down server keeps failing checks whatever the store does
+	hs.Shutdown()
+	time.Sleep(10 * time.Millisecond)
+	if got := status(t, hs, "blog.BlogService"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Check(blog.BlogService) after Shutdown got %v, want NOT_SERVING", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Watch() did not return once its context was done")
+	}
+}
@@ -0,0 +1,169 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// RequestIDHeader is the metadata key of the request ID of a call. A call
+// keeps the ID its client sends, or gets a new one, and the ID is sent back
+// in the response header.
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLength bounds the request IDs taken from clients, so that they
+// cannot blow up log lines.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID returns the request ID of the call of ctx, or "" outside of a
+// logged call.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestID returns the request ID the client sent, or a new random one.
+func requestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(RequestIDHeader); len(values) > 0 && values[0] != "" && len(values[0]) <= maxRequestIDLength {
+		return values[0]
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// size is the encoded size of msg, 0 for a message that is not protobuf.
+func size(msg interface{}) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return 0
+}
+
+// callFields are the fields of every log line, up to the payloads. The
+// trace_id of traced calls ties the line to their spans.
+func callFields(ctx context.Context, method string, start time.Time, err error) []field {
+	st := status.Convert(err)
+	fields := []field{
+		{"level", level(st.Code())},
+		{"method", method},
+		{"peer", peerAddr(ctx)},
+		{"request_id", RequestID(ctx)},
+	}
+	if id := tracing.TraceIDFromContext(ctx); id != "" {
+		fields = append(fields, field{"trace_id", id})
+	}
+	fields = append(fields,
+		field{"code", st.Code().String()},
+		field{"duration_ms", float64(time.Since(start).Microseconds()) / 1000},
+	)
+	if err != nil {
+		fields = append(fields, field{"error", st.Message()})
+	}
+	return fields
+}
+
+// UnaryServerInterceptor logs unary calls.
+func UnaryServerInterceptor(l *Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		id := requestID(ctx)
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+
+		res, err := handler(ctx, req)
+
+		fields := callFields(ctx, info.FullMethod, start, err)
+		fields = append(fields, field{"request_size", size(req)})
+		if err == nil {
+			fields = append(fields, field{"response_size", size(res)})
+		}
+		if p := l.payload(req); p != nil {
+			fields = append(fields, field{"request", p})
+		}
+		if p := l.payload(res); p != nil && err == nil {
+			fields = append(fields, field{"response", p})
+		}
+		l.write(fields)
+
+		return res, err
+	}
+}
+
+// StreamServerInterceptor logs streaming calls, with the number and total
+// size of the messages received and sent. Their messages are not logged.
+func StreamServerInterceptor(l *Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		id := requestID(ss.Context())
+		ss.SetHeader(metadata.Pairs(RequestIDHeader, id))
+		stream := &countingStream{ServerStream: ss, ctx: context.WithValue(ss.Context(), requestIDKey{}, id)}
+
+		err := handler(srv, stream)
+
+		fields := callFields(stream.ctx, info.FullMethod, start, err)
+		fields = append(fields,
+			field{"request_count", stream.received},
+			field{"request_size", stream.receivedSize},
+			field{"response_count", stream.sent},
+			field{"response_size", stream.sentSize},
+		)
+		l.write(fields)
+
+		return err
+	}
+}
+
+// countingStream counts the messages of a stream, and gives its handler the
+// context with the request ID.
+type countingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	received, receivedSize int
+	sent, sentSize         int
+}
+
+func (s *countingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *countingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+		s.receivedSize += size(m)
+	}
+	return err
+}
+
+func (s *countingStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		s.sentSize += size(m)
+	}
+	return err
+}
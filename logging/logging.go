@@ -0,0 +1,216 @@
+// Package logging writes one structured log line per gRPC call, as JSON or
+// logfmt, with the method, peer, duration, status code, request ID and
+// payload sizes of the call.
+//
+// Unary calls can also log their request and response messages, with the
+// values of sensitive fields redacted.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Formats are the formats a Logger can write.
+var Formats = []string{"logfmt", "json"}
+
+// redacted replaces the values of redacted string fields.
+const redacted = "[REDACTED]"
+
+// Options configure a Logger.
+type Options struct {
+	Format   string   // one of Formats
+	Payloads bool     // log the request and response messages of unary calls
+	Redact   []string // names of the fields whose values logged messages hide, at any depth
+}
+
+// Logger writes the log lines of calls to a writer.
+type Logger struct {
+	mu     sync.Mutex // serialises the lines of concurrent calls
+	w      io.Writer
+	opts   Options
+	redact map[protoreflect.Name]bool
+
+	now func() time.Time // for tests
+}
+
+// New returns a Logger writing to w.
+func New(w io.Writer, opts Options) (*Logger, error) {
+	if !isFormat(opts.Format) {
+		return nil, fmt.Errorf("unknown log format %q, want one of %v", opts.Format, Formats)
+	}
+
+	redact := make(map[protoreflect.Name]bool, len(opts.Redact))
+	for _, name := range opts.Redact {
+		redact[protoreflect.Name(name)] = true
+	}
+	return &Logger{w: w, opts: opts, redact: redact, now: time.Now}, nil
+}
+
+func isFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// field is a key and value of a log line.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// payload is a message logged as its JSON.
+type payload json.RawMessage
+
+// level is the severity of a call that ended with code: errors are the codes
+// that point at a problem of the server rather than of the call.
+func level(code codes.Code) string {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unavailable:
+		return "error"
+	}
+	return "info"
+}
+
+// write writes one line with the time and the fields, in order.
+func (l *Logger) write(fields []field) {
+	fields = append([]field{{"time", l.now().UTC().Format(time.RFC3339Nano)}}, fields...)
+
+	var buf bytes.Buffer
+	if l.opts.Format == "json" {
+		writeJSON(&buf, fields)
+	} else {
+		writeLogfmt(&buf, fields)
+	}
+	buf.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(buf.Bytes())
+}
+
+func writeJSON(buf *bytes.Buffer, fields []field) {
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		if p, ok := f.value.(payload); ok {
+			buf.Write(p)
+			continue
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(f.value))
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+}
+
+func writeLogfmt(buf *bytes.Buffer, fields []field) {
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(f.key)
+		buf.WriteByte('=')
+
+		var value string
+		switch v := f.value.(type) {
+		case payload:
+			value = string(v)
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			value = fmt.Sprint(v)
+		}
+		if value == "" || strings.ContainsAny(value, " =\"\\\t\n") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(value)
+	}
+}
+
+// payload returns msg as compact JSON with the redacted fields hidden, or
+// nil if payloads are not logged.
+func (l *Logger) payload(msg interface{}) interface{} {
+	m, ok := msg.(proto.Message)
+	if !l.opts.Payloads || !ok {
+		return nil
+	}
+
+	if len(l.redact) > 0 {
+		m = proto.Clone(m)
+		l.redactMessage(m.ProtoReflect())
+	}
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return fmt.Sprintf("cannot log the message: %v", err)
+	}
+	// protojson spaces its output at random, compacting it keeps lines stable
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return fmt.Sprintf("cannot log the message: %v", err)
+	}
+	return payload(buf.Bytes())
+}
+
+// redactMessage replaces the strings of the redacted fields of m and clears
+// the other redacted fields, looking into nested messages.
+func (l *Logger) redactMessage(m protoreflect.Message) {
+	// m is only changed once Range is done with it
+	var hidden []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case l.redact[fd.Name()] && fd.Kind() == protoreflect.StringKind && fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				list.Set(i, protoreflect.ValueOfString(redacted))
+			}
+		case l.redact[fd.Name()]:
+			hidden = append(hidden, fd)
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					l.redactMessage(v.Message())
+					return true
+				})
+			}
+		case fd.Message() != nil && fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				l.redactMessage(list.Get(i).Message())
+			}
+		case fd.Message() != nil:
+			l.redactMessage(v.Message())
+		}
+		return true
+	})
+
+	for _, fd := range hidden {
+		if fd.Kind() == protoreflect.StringKind && !fd.IsMap() {
+			m.Set(fd, protoreflect.ValueOfString(redacted))
+		} else {
+			m.Clear(fd)
+		}
+	}
+}
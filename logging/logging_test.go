@@ -0,0 +1,191 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestLogger(t *testing.T, opts Options) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l, err := New(&buf, opts)
+	if err != nil {
+		t.Fatalf("New(%+v) had unexpected error: %v", opts, err)
+	}
+	l.now = func() time.Time { return time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return l, &buf
+}
+
+// callContext returns the context of a call from 10.0.0.1 with the given
+// incoming metadata.
+func callContext(md metadata.MD) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4242}})
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, Options{Format: "xml"}); err == nil {
+		t.Errorf("New(xml) got no error")
+	}
+}
+
+func TestWrite_Formats(t *testing.T) {
+	fields := []field{
+		{"method", "/blog.BlogService/ReadBlog"},
+		{"error", `blog "x" not found`},
+		{"peer", ""},
+		{"duration_ms", 1.5},
+		{"request", payload(`{"blogId":"x"}`)},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"logfmt", `time=2022-01-02T03:04:05Z method=/blog.BlogService/ReadBlog error="blog \"x\" not found" peer="" duration_ms=1.5 request="{\"blogId\":\"x\"}"` + "\n"},
+		{"json", `{"time":"2022-01-02T03:04:05Z","method":"/blog.BlogService/ReadBlog","error":"blog \"x\" not found","peer":"","duration_ms":1.5,"request":{"blogId":"x"}}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		l, buf := newTestLogger(t, Options{Format: tt.format})
+		l.write(fields)
+		if got := buf.String(); got != tt.want {
+			t.Errorf("write(%s) got %s, want %s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestPayload_Redact(t *testing.T) {
+	l, _ := newTestLogger(t, Options{Format: "json", Payloads: true, Redact: []string{"content", "tags", "created_at"}})
+	req := &blogpb.BatchCreateBlogsRequest{Blogs: []*blogpb.Blog{{
+		Title:   "Hello",
+		Content: "secret",
+		Tags:    []string{"a", "b"},
+	}}}
+
+	got := string(l.payload(req).(payload))
+	want := `{"blogs":[{"title":"Hello","content":"[REDACTED]","tags":["[REDACTED]","[REDACTED]"]}]}`
+	if got != want {
+		t.Errorf("payload() got %s, want %s", got, want)
+	}
+	if req.GetBlogs()[0].GetContent() != "secret" {
+		t.Errorf("payload() changed the logged message, want it left as it is")
+	}
+
+	l, _ = newTestLogger(t, Options{Format: "json"})
+	if got := l.payload(req); got != nil {
+		t.Errorf("payload() without Payloads got %v, want nil", got)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l, buf := newTestLogger(t, Options{Format: "json", Payloads: true})
+	req := &blogpb.ReadBlogRequest{BlogId: "x"}
+	info := &grpc.UnaryServerInfo{FullMethod: "/blog.BlogService/ReadBlog"}
+	ctx := callContext(metadata.Pairs(RequestIDHeader, "req-1"))
+	ctx = tracing.Extract(ctx, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "")
+
+	var handlerID string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerID = RequestID(ctx)
+		return nil, status.Error(codes.NotFound, "blog x not found")
+	}
+	if _, err := UnaryServerInterceptor(l)(ctx, req, info, handler); status.Code(err) != codes.NotFound {
+		t.Errorf("UnaryServerInterceptor() got error %v, want the NotFound of the handler", err)
+	}
+	if handlerID != "req-1" {
+		t.Errorf("RequestID() in the handler got %q, want the req-1 of the client", handlerID)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("UnaryServerInterceptor() logged %s, want a JSON line", buf)
+	}
+	want := map[string]interface{}{
+		"level":        "info",
+		"method":       "/blog.BlogService/ReadBlog",
+		"peer":         "10.0.0.1:4242",
+		"request_id":   "req-1",
+		"trace_id":     "0af7651916cd43dd8448eb211c80319c",
+		"code":         "NotFound",
+		"error":        "blog x not found",
+		"request_size": float64(proto.Size(req)),
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("UnaryServerInterceptor() logged %s=%v, want %v", key, got[key], value)
+		}
+	}
+	if _, ok := got["response_size"]; ok {
+		t.Errorf("UnaryServerInterceptor() logged a response_size for a failed call")
+	}
+}
+
+// fakeStream is a server stream that receives the messages of recv.
+type fakeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv []proto.Message
+	sent int
+}
+
+func (s *fakeStream) Context() context.Context    { return s.ctx }
+func (s *fakeStream) SetHeader(metadata.MD) error { return nil }
+func (s *fakeStream) SendMsg(m interface{}) error { s.sent++; return nil }
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	if len(s.recv) == 0 {
+		return status.Error(codes.Canceled, "no more messages")
+	}
+	proto.Merge(m.(proto.Message), s.recv[0])
+	s.recv = s.recv[1:]
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	l, buf := newTestLogger(t, Options{Format: "logfmt"})
+	msg := &blogpb.ImportBlogsRequest{Blog: &blogpb.Blog{Title: "Hello"}}
+	ss := &fakeStream{ctx: callContext(nil), recv: []proto.Message{msg, msg}}
+	info := &grpc.StreamServerInfo{FullMethod: "/blog.BlogService/ImportBlogs", IsClientStream: true}
+
+	var handlerID string
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		handlerID = RequestID(stream.Context())
+		for {
+			if err := stream.RecvMsg(&blogpb.ImportBlogsRequest{}); err != nil {
+				break
+			}
+		}
+		return stream.SendMsg(&blogpb.ImportBlogsResponse{CreatedCount: 2})
+	}
+	if err := StreamServerInterceptor(l)(nil, ss, info, handler); err != nil {
+		t.Fatalf("StreamServerInterceptor() had unexpected error: %v", err)
+	}
+
+	if len(handlerID) != 32 {
+		t.Errorf("RequestID() in the handler got %q, want a new 32 digit ID", handlerID)
+	}
+	line := buf.String()
+	for _, want := range []string{
+		" level=info method=/blog.BlogService/ImportBlogs peer=10.0.0.1:4242 request_id=" + handlerID + " code=OK ",
+		fmt.Sprintf(" request_count=2 request_size=%d response_count=1 ", 2*proto.Size(msg)),
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("StreamServerInterceptor() logged %q, want it to contain %q", line, want)
+		}
+	}
+}
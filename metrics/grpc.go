@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// RPCMetrics are the metrics of the calls of one side, server or client,
+// labelled with the type of the call (unary, client_stream, server_stream or
+// bidi_stream), its service and its method:
+//
+//	grpc_<side>_started_total        calls started
+//	grpc_<side>_handled_total        calls finished, also labelled with their grpc_code
+//	grpc_<side>_handling_seconds     histogram of the duration of finished calls
+//	grpc_<side>_in_flight            calls started but not finished
+//	grpc_<side>_msg_received_total   messages received
+//	grpc_<side>_msg_sent_total       messages sent
+type RPCMetrics struct {
+	started     *Counter
+	handled     *Counter
+	handling    *Histogram
+	inFlight    *Gauge
+	msgReceived *Counter
+	msgSent     *Counter
+}
+
+// NewServerMetrics returns the metrics of the calls a server handles,
+// registered on r.
+func NewServerMetrics(r *Registry) *RPCMetrics {
+	return newRPCMetrics(r, "server", "handled by the server")
+}
+
+// NewClientMetrics returns the metrics of the calls a client makes,
+// registered on r.
+func NewClientMetrics(r *Registry) *RPCMetrics {
+	return newRPCMetrics(r, "client", "made by the client")
+}
+
+func newRPCMetrics(r *Registry, side, calls string) *RPCMetrics {
+	name := "grpc_" + side + "_"
+	labels := []string{"grpc_type", "grpc_service", "grpc_method"}
+	return &RPCMetrics{
+		started:     r.Counter(name+"started_total", "Total number of RPCs started, "+calls+".", labels...),
+		handled:     r.Counter(name+"handled_total", "Total number of RPCs finished, "+calls+", by status code.", append(labels, "grpc_code")...),
+		handling:    r.Histogram(name+"handling_seconds", "Duration in seconds of the RPCs "+calls+".", DefaultBuckets, labels...),
+		inFlight:    r.Gauge(name+"in_flight", "Number of RPCs "+calls+" that have started but not finished.", labels...),
+		msgReceived: r.Counter(name+"msg_received_total", "Total number of messages received in RPCs "+calls+".", labels...),
+		msgSent:     r.Counter(name+"msg_sent_total", "Total number of messages sent in RPCs "+calls+".", labels...),
+	}
+}
+
+// call is one call being recorded.
+type call struct {
+	m      *RPCMetrics
+	labels []string
+	start  time.Time
+	once   sync.Once
+}
+
+// start records the start of a call of fullMethod, /package.Service/Method.
+func (m *RPCMetrics) start(fullMethod string, clientStream, serverStream bool) *call {
+	service, method := "unknown", "unknown"
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		service, method = strings.TrimPrefix(fullMethod[:i], "/"), fullMethod[i+1:]
+	}
+	c := &call{m: m, labels: []string{callType(clientStream, serverStream), service, method}, start: time.Now()}
+
+	m.started.Inc(c.labels...)
+	m.inFlight.Inc(c.labels...)
+	return c
+}
+
+func callType(clientStream, serverStream bool) string {
+	switch {
+	case clientStream && serverStream:
+		return "bidi_stream"
+	case clientStream:
+		return "client_stream"
+	case serverStream:
+		return "server_stream"
+	}
+	return "unary"
+}
+
+// finish records the end of c with the status of err, once.
+func (c *call) finish(err error) {
+	c.once.Do(func() {
+		c.m.inFlight.Dec(c.labels...)
+		c.m.handled.Inc(append(c.labels, status.Code(err).String())...)
+		c.m.handling.Observe(time.Since(c.start).Seconds(), c.labels...)
+	})
+}
+
+func (c *call) received() { c.m.msgReceived.Inc(c.labels...) }
+func (c *call) sent()     { c.m.msgSent.Inc(c.labels...) }
+
+// UnaryServerInterceptor records the unary calls of a server in m.
+func UnaryServerInterceptor(m *RPCMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		c := m.start(info.FullMethod, false, false)
+		c.received()
+		res, err := handler(ctx, req)
+		if err == nil {
+			c.sent()
+		}
+		c.finish(err)
+		return res, err
+	}
+}
+
+// StreamServerInterceptor records the streaming calls of a server in m.
+func StreamServerInterceptor(m *RPCMetrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		c := m.start(info.FullMethod, info.IsClientStream, info.IsServerStream)
+		err := handler(srv, &serverStream{ServerStream: ss, c: c})
+		c.finish(err)
+		return err
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	c *call
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.c.received()
+	}
+	return err
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.c.sent()
+	}
+	return err
+}
+
+// UnaryClientInterceptor records the unary calls of a client in m.
+func UnaryClientInterceptor(m *RPCMetrics) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		c := m.start(method, false, false)
+		c.sent()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			c.received()
+		}
+		c.finish(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor records the streaming calls of a client in m. A
+// call finishes when receiving from it reports its end, so the calls a
+// client gives up on before then stay in flight.
+func StreamClientInterceptor(m *RPCMetrics) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		c := m.start(method, desc.ClientStreams, desc.ServerStreams)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			c.finish(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, c: c, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	c             *call
+	serverStreams bool
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.c.sent()
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.c.finish(nil)
+	case err != nil:
+		s.c.finish(err)
+	default:
+		s.c.received()
+		// the single response of a client stream ends the call
+		if !s.serverStreams {
+			s.c.finish(nil)
+		}
+	}
+	return err
+}
@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func written(t *testing.T, r *Registry) string {
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatalf("Write() had unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("unused_total", "Never incremented.")
+	calls := r.Counter("calls_total", "Calls\nmade.", "method")
+	calls.Inc("b")
+	calls.Add(2, `a"\`)
+	inFlight := r.Gauge("in_flight", "Calls in flight.")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	latency := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "method")
+	latency.Observe(0.05, "a")
+	latency.Observe(0.1, "a")
+	latency.Observe(3, "a")
+
+	want := `# HELP calls_total Calls\nmade.
+# TYPE calls_total counter
+calls_total{method="a\"\\"} 2
+calls_total{method="b"} 1
+# HELP in_flight Calls in flight.
+# TYPE in_flight gauge
+in_flight 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="a",le="0.1"} 2
+latency_seconds_bucket{method="a",le="1"} 2
+latency_seconds_bucket{method="a",le="+Inf"} 3
+latency_seconds_sum{method="a"} 3.15
+latency_seconds_count{method="a"} 3
+`
+	if got := written(t, r); got != want {
+		t.Errorf("Write() got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("calls_total", "Calls.", "method").Inc("a")
+	r.Counter("calls_total", "Calls.", "method").Inc("a")
+	if got := written(t, r); !strings.Contains(got, `calls_total{method="a"} 2`) {
+		t.Errorf("Write() got %s, want both counters to be the same", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Gauge(calls_total) did not panic, want a panic for a counter registered as a gauge")
+		}
+	}()
+	r.Gauge("calls_total", "Calls.", "method")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("calls_total", "Calls.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Handler() got Content-Type %q, want the Prometheus text format", got)
+	}
+	if got := rec.Body.String(); !strings.Contains(got, "calls_total 1\n") {
+		t.Errorf("Handler() got body %s, want the counter", got)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	r := NewRegistry()
+	interceptor := UnaryServerInterceptor(NewServerMetrics(r))
+	info := &grpc.UnaryServerInfo{FullMethod: "/blog.BlogService/ReadBlog"}
+
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "blog", nil }
+	notFound := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no blog")
+	}
+	interceptor(context.Background(), "req", info, ok)
+	interceptor(context.Background(), "req", info, notFound)
+
+	got := written(t, r)
+	for _, want := range []string{
+		`grpc_server_started_total{grpc_type="unary",grpc_service="blog.BlogService",grpc_method="ReadBlog"} 2`,
+		`grpc_server_handled_total{grpc_type="unary",grpc_service="blog.BlogService",grpc_method="ReadBlog",grpc_code="OK"} 1`,
+		`grpc_server_handled_total{grpc_type="unary",grpc_service="blog.BlogService",grpc_method="ReadBlog",grpc_code="NotFound"} 1`,
+		`grpc_server_in_flight{grpc_type="unary",grpc_service="blog.BlogService",grpc_method="ReadBlog"} 0`,
+		`grpc_server_msg_received_total{grpc_type="unary",grpc_service="blog.BlogService",grpc_method="ReadBlog"} 2`,
+		`grpc_server_msg_sent_total{grpc_type="unary",grpc_service="blog.BlogService",grpc_method="ReadBlog"} 1`,
+		`grpc_server_handling_seconds_count{grpc_type="unary",grpc_service="blog.BlogService",grpc_method="ReadBlog"} 2`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("Write() got\n%s\nwant it to contain %s", got, want)
+		}
+	}
+}
+
+// fakeServerStream receives n messages, then io.EOF.
+type fakeServerStream struct {
+	grpc.ServerStream
+	n int
+}
+
+func (s *fakeServerStream) RecvMsg(m interface{}) error {
+	if s.n == 0 {
+		return io.EOF
+	}
+	s.n--
+	return nil
+}
+
+func (s *fakeServerStream) SendMsg(m interface{}) error { return nil }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	r := NewRegistry()
+	info := &grpc.StreamServerInfo{FullMethod: "/calculator.CalculatorService/FindMaximum", IsClientStream: true, IsServerStream: true}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		for ss.RecvMsg(nil) == nil {
+			ss.SendMsg(nil)
+		}
+		return errors.New("unexpected")
+	}
+	StreamServerInterceptor(NewServerMetrics(r))(nil, &fakeServerStream{n: 3}, info, handler)
+
+	got := written(t, r)
+	labels := `{grpc_type="bidi_stream",grpc_service="calculator.CalculatorService",grpc_method="FindMaximum"`
+	for _, want := range []string{
+		"grpc_server_msg_received_total" + labels + "} 3",
+		"grpc_server_msg_sent_total" + labels + "} 3",
+		"grpc_server_handled_total" + labels + `,grpc_code="Unknown"} 1`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("Write() got\n%s\nwant it to contain %s", got, want)
+		}
+	}
+}
+
+// fakeClientStream returns the errors of recv from RecvMsg, in order.
+type fakeClientStream struct {
+	grpc.ClientStream
+	recv []error
+}
+
+func (s *fakeClientStream) SendMsg(m interface{}) error { return nil }
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	err := s.recv[0]
+	s.recv = s.recv[1:]
+	return err
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	tests := []struct {
+		name string
+		desc *grpc.StreamDesc
+		recv []error
+		want string
+	}{
+		{"client stream", &grpc.StreamDesc{ClientStreams: true}, []error{nil}, `grpc_type="client_stream",grpc_service="s",grpc_method="m",grpc_code="OK"} 1`},
+		{"server stream", &grpc.StreamDesc{ServerStreams: true}, []error{nil, nil, io.EOF}, `grpc_type="server_stream",grpc_service="s",grpc_method="m",grpc_code="OK"} 1`},
+		{"failed stream", &grpc.StreamDesc{ServerStreams: true}, []error{status.Error(codes.Unavailable, "down")}, `grpc_type="server_stream",grpc_service="s",grpc_method="m",grpc_code="Unavailable"} 1`},
+	}
+
+	for _, tt := range tests {
+		r := NewRegistry()
+		streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &fakeClientStream{recv: tt.recv}, nil
+		}
+		cs, _ := StreamClientInterceptor(NewClientMetrics(r))(context.Background(), tt.desc, nil, "/s/m", streamer)
+		cs.SendMsg(nil)
+		for cs.RecvMsg(nil) == nil && tt.desc.ServerStreams {
+		}
+
+		got := written(t, r)
+		if !strings.Contains(got, "grpc_client_handled_total{"+tt.want+"\n") {
+			t.Errorf("%s: Write() got\n%s\nwant it to contain grpc_client_handled_total{%s", tt.name, got, tt.want)
+		}
+		if !strings.Contains(got, "grpc_client_in_flight{"+strings.SplitN(tt.want, `,grpc_code`, 2)[0]+"} 0\n") {
+			t.Errorf("%s: Write() got\n%s\nwant no call in flight", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func blogRoutes(c blogpb.BlogServiceClient) []route {
+	return []route{
+		newRoute(http.MethodPost, "/v1/blogs", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{}}
+			if err := decodeBody(r, req.Blog); err != nil {
+				return nil, err
+			}
+			return c.CreateBlog(ctx, req)
+		}),
+		newRoute(http.MethodGet, "/v1/blogs", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &blogpb.ListBlogRequest{}
+			if err := decodeQuery(r.URL.Query(), req); err != nil {
+				return nil, err
+			}
+			return c.ListBlogsPage(ctx, req)
+		}),
+		newRoute(http.MethodGet, "/v1/blogs:search", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &blogpb.SearchBlogsRequest{}
+			if err := decodeQuery(r.URL.Query(), req); err != nil {
+				return nil, err
+			}
+			return c.SearchBlogs(ctx, req)
+		}),
+		newRoute(http.MethodGet, "/v1/blogs/{id}", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &blogpb.ReadBlogRequest{}
+			if err := decodeQuery(r.URL.Query(), req); err != nil {
+				return nil, err
+			}
+			req.BlogId = r.vars["id"]
+			return c.ReadBlog(ctx, req)
+		}),
+		newRoute(http.MethodPatch, "/v1/blogs/{id}", func(ctx context.Context, r *request) (interface{}, error) {
+			req, err := updateRequest(r, true)
+			if err != nil {
+				return nil, err
+			}
+			return c.UpdateBlog(ctx, req)
+		}),
+		newRoute(http.MethodPut, "/v1/blogs/{id}", func(ctx context.Context, r *request) (interface{}, error) {
+			req, err := updateRequest(r, false)
+			if err != nil {
+				return nil, err
+			}
+			return c.UpdateBlog(ctx, req)
+		}),
+		newRoute(http.MethodDelete, "/v1/blogs/{id}", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &blogpb.DeleteBlogRequest{}
+			if err := decodeQuery(r.URL.Query(), req); err != nil {
+				return nil, err
+			}
+			req.BlogId = r.vars["id"]
+			return c.DeleteBlog(ctx, req)
+		}),
+		newRoute(http.MethodPost, "/v1/blogs/{id}:restore", func(ctx context.Context, r *request) (interface{}, error) {
+			return c.RestoreBlog(ctx, &blogpb.RestoreBlogRequest{BlogId: r.vars["id"]})
+		}),
+		newRoute(http.MethodPost, "/v1/blogs/{id}:purge", func(ctx context.Context, r *request) (interface{}, error) {
+			return c.PurgeBlog(ctx, &blogpb.PurgeBlogRequest{BlogId: r.vars["id"]})
+		}),
+		newRoute(http.MethodGet, "/v1/blogs/{id}/revisions", func(ctx context.Context, r *request) (interface{}, error) {
+			return c.ListBlogRevisions(ctx, &blogpb.ListBlogRevisionsRequest{BlogId: r.vars["id"]})
+		}),
+		newRoute(http.MethodGet, "/v1/tags", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &blogpb.ListTagsRequest{}
+			if err := decodeQuery(r.URL.Query(), req); err != nil {
+				return nil, err
+			}
+			return c.ListTags(ctx, req)
+		}),
+	}
+}
+
+// updateRequest returns the UpdateBlogRequest of a PATCH or PUT of the blog
+// in the body of r. The query may give expected_revision and update_mask;
+// without an update_mask, a patch only updates the fields the body sets.
+func updateRequest(r *request, patch bool) (*blogpb.UpdateBlogRequest, error) {
+	req := &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{}}
+	if err := decodeBody(r, req.Blog); err != nil {
+		return nil, err
+	}
+	if err := decodeQuery(r.URL.Query(), req); err != nil {
+		return nil, err
+	}
+
+	id := r.vars["id"]
+	if req.Blog.Id != "" && req.Blog.Id != id {
+		return nil, invalidArgument("id", "the body is blog %q, not %q", req.Blog.Id, id)
+	}
+	req.Blog.Id = id
+
+	if patch && req.UpdateMask == nil {
+		var paths []string
+		req.Blog.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if fd.Name() != "id" {
+				paths = append(paths, string(fd.Name()))
+			}
+			return true
+		})
+		if len(paths) == 0 {
+			return nil, invalidArgument("body", "the body sets no field to update")
+		}
+		req.UpdateMask = &fieldmaskpb.FieldMask{Paths: paths}
+	}
+	return req, nil
+}
@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorpb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func calculatorRoutes(c calculatorpb.CalculatorServiceClient) []route {
+	return []route{
+		newRoute(http.MethodPost, "/v1/calculator/sum", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &calculatorpb.SumRequest{}
+			if err := decodeBody(r, req); err != nil {
+				return nil, err
+			}
+			return c.Sum(ctx, req)
+		}),
+		newRoute(http.MethodPost, "/v1/calculator/square-root", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &calculatorpb.SquareRootRequest{}
+			if err := decodeBody(r, req); err != nil {
+				return nil, err
+			}
+			return c.SquareRoot(ctx, req)
+		}),
+		newRoute(http.MethodPost, "/v1/calculator/prime-number-decomposition", func(ctx context.Context, r *request) (interface{}, error) {
+			req := &calculatorpb.PrimeNumberDecompositionRequest{}
+			if err := decodeBody(r, req); err != nil {
+				return nil, err
+			}
+			stream, err := c.PrimeNumberDecomposition(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			var factors []proto.Message
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					return factors, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				factors = append(factors, res)
+			}
+		}),
+		newRoute(http.MethodPost, "/v1/calculator/compute-average", func(ctx context.Context, r *request) (interface{}, error) {
+			reqs, err := decodeBodyList(r, func() proto.Message { return &calculatorpb.ComputeAverageRequest{} })
+			if err != nil {
+				return nil, err
+			}
+			stream, err := c.ComputeAverage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, req := range reqs {
+				// on io.EOF the server has ended the call, and CloseAndRecv tells why
+				if err := stream.Send(req.(*calculatorpb.ComputeAverageRequest)); err != nil {
+					break
+				}
+			}
+			return stream.CloseAndRecv()
+		}),
+		newRoute(http.MethodPost, "/v1/calculator/find-maximum", func(ctx context.Context, r *request) (interface{}, error) {
+			reqs, err := decodeBodyList(r, func() proto.Message { return &calculatorpb.FindMaximumRequest{} })
+			if err != nil {
+				return nil, err
+			}
+			stream, err := c.FindMaximum(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			// the maximums are received while the numbers are sent, so that
+			// neither side waits on the other for long streams
+			var maximums []proto.Message
+			received := make(chan error, 1)
+			go func() {
+				for {
+					res, err := stream.Recv()
+					if err == io.EOF {
+						received <- nil
+						return
+					}
+					if err != nil {
+						received <- err
+						return
+					}
+					maximums = append(maximums, res)
+				}
+			}()
+
+			for _, req := range reqs {
+				// on io.EOF the server has ended the call, and Recv tells why
+				if err := stream.Send(req.(*calculatorpb.FindMaximumRequest)); err != nil {
+					break
+				}
+			}
+			stream.CloseSend()
+			if err := <-received; err != nil {
+				return nil, err
+			}
+			return maximums, nil
+		}),
+	}
+}
@@ -0,0 +1,233 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mirageruler/grpc-go-course/rpcerr"
+
+	// so that the details of errors can be written as JSON
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// maxBodySize bounds the request bodies read, like the default limit of gRPC
+// messages.
+const maxBodySize = 4 << 20
+
+func invalidArgument(field, format string, a ...interface{}) error {
+	msg := fmt.Sprintf(format, a...)
+	return rpcerr.New(codes.InvalidArgument, msg, rpcerr.BadRequest(rpcerr.Violation(field, msg)))
+}
+
+// readBody returns the body of r, or nil if it is empty.
+func readBody(r *request) ([]byte, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, invalidArgument("body", "cannot read the body: %v", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// decodeBody decodes the JSON body of r into msg, leaving msg empty when
+// there is no body.
+func decodeBody(r *request, msg proto.Message) error {
+	data, err := readBody(r)
+	if err != nil || data == nil {
+		return err
+	}
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return invalidArgument("body", "invalid body: %v", err)
+	}
+	return nil
+}
+
+// decodeBodyList decodes the JSON array body of r into messages returned by
+// newMsg, for the requests of a client stream.
+func decodeBodyList(r *request, newMsg func() proto.Message) ([]proto.Message, error) {
+	data, err := readBody(r)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, invalidArgument("body", "the body must be a JSON array: %v", err)
+	}
+	msgs := make([]proto.Message, 0, len(items))
+	for i, item := range items {
+		msg := newMsg()
+		if err := protojson.Unmarshal(item, msg); err != nil {
+			return nil, invalidArgument(fmt.Sprintf("body[%d]", i), "invalid body item %d: %v", i, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// decodeQuery sets the fields of msg named by the query parameters, by their
+// proto or JSON name. Repeated fields take every value of their parameter,
+// enums take names or numbers and a FieldMask takes comma-separated paths.
+func decodeQuery(query url.Values, msg proto.Message) error {
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	for name, values := range query {
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			return invalidArgument(name, "unknown query parameter %q", name)
+		}
+
+		if fd.IsList() {
+			list := m.Mutable(fd).List()
+			for _, s := range values {
+				v, err := parseValue(fd, s)
+				if err != nil {
+					return err
+				}
+				list.Append(v)
+			}
+			continue
+		}
+
+		if len(values) > 1 {
+			return invalidArgument(name, "query parameter %q can only be given once", name)
+		}
+		if fd.Message() != nil && fd.Message().FullName() == "google.protobuf.FieldMask" {
+			mask := &fieldmaskpb.FieldMask{Paths: strings.Split(values[0], ",")}
+			m.Set(fd, protoreflect.ValueOfMessage(mask.ProtoReflect()))
+			continue
+		}
+		v, err := parseValue(fd, values[0])
+		if err != nil {
+			return err
+		}
+		m.Set(fd, v)
+	}
+	return nil
+}
+
+// parseValue parses s as a value of the scalar or enum field fd.
+func parseValue(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	var (
+		v   protoreflect.Value
+		err error
+	)
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 64)
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 64)
+		v = protoreflect.ValueOfUint64(n)
+	case protoreflect.FloatKind:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		v = protoreflect.ValueOfFloat32(float32(f))
+	case protoreflect.DoubleKind:
+		var f float64
+		f, err = strconv.ParseFloat(s, 64)
+		v = protoreflect.ValueOfFloat64(f)
+	case protoreflect.EnumKind:
+		if value := fd.Enum().Values().ByName(protoreflect.Name(s)); value != nil {
+			return protoreflect.ValueOfEnum(value.Number()), nil
+		}
+		// unknown numbers are left to the validation of the server
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 32)
+		v = protoreflect.ValueOfEnum(protoreflect.EnumNumber(n))
+	default:
+		return v, invalidArgument(string(fd.Name()), "query parameter %q cannot be set from the query", fd.Name())
+	}
+
+	if err != nil {
+		return v, invalidArgument(string(fd.Name()), "invalid query parameter %q: %q is not a %v", fd.Name(), s, fd.Kind())
+	}
+	return v, nil
+}
+
+// writeResponse writes res, a proto.Message or the []proto.Message of a
+// server stream, as JSON.
+func writeResponse(w http.ResponseWriter, res interface{}) {
+	var buf bytes.Buffer
+	switch res := res.(type) {
+	case proto.Message:
+		data, err := protojson.Marshal(res)
+		if err != nil {
+			writeError(w, status.Errorf(codes.Internal, "cannot write the response: %v", err))
+			return
+		}
+		buf.Write(data)
+	case []proto.Message:
+		buf.WriteByte('[')
+		for i, msg := range res {
+			data, err := protojson.Marshal(msg)
+			if err != nil {
+				writeError(w, status.Errorf(codes.Internal, "cannot write the response: %v", err))
+				return
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(data)
+		}
+		buf.WriteByte(']')
+	default:
+		writeError(w, status.Errorf(codes.Internal, "cannot write a response of type %T", res))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// writeError writes the status of err with the HTTP status of its code.
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	writeStatus(w, httpStatus(st.Code()), st)
+}
+
+func writeStatus(w http.ResponseWriter, code int, st *status.Status) {
+	data, err := protojson.Marshal(st.Proto())
+	if err != nil {
+		// details of unknown types cannot be written, but the rest can
+		data, _ = protojson.Marshal(status.New(st.Code(), st.Message()).Proto())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
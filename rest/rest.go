@@ -0,0 +1,196 @@
+// Package rest serves the BlogService and the CalculatorService as HTTP/JSON.
+//
+// Every route calls the gRPC service through a client, so the interceptors
+// of the server, such as authentication and validation, apply to REST calls
+// too. Request and response bodies are the JSON mapping of the protobuf
+// messages, written by protojson; streams are JSON arrays of messages. Errors
+// are the JSON of their google.rpc.Status, with the HTTP status of its code.
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorpb"
+	"github.com/mirageruler/grpc-go-course/logging"
+	"github.com/mirageruler/grpc-go-course/tracing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Handler serves the routes of the services it has a client for.
+type Handler struct {
+	routes []route
+}
+
+// route maps the HTTP method and path of a request to a gRPC call.
+type route struct {
+	method string
+	path   []string // segments, a {name} segment matches any value
+	call   func(ctx context.Context, r *request) (interface{}, error)
+}
+
+// request is an HTTP request with the values of the {name} segments of the
+// path of its route.
+type request struct {
+	*http.Request
+	vars map[string]string
+}
+
+// New returns the Handler of the given clients. A nil client leaves the
+// routes of its service out.
+//
+// The BlogService routes are:
+//
+//	POST   /v1/blogs                  CreateBlog, the body is the blog
+//	GET    /v1/blogs                  ListBlogsPage, with the request in the query
+//	GET    /v1/blogs:search           SearchBlogs, with the request in the query
+//	GET    /v1/blogs/{id}             ReadBlog
+//	PATCH  /v1/blogs/{id}             UpdateBlog of the fields of the body, or of update_mask
+//	PUT    /v1/blogs/{id}             UpdateBlog of every field
+//	DELETE /v1/blogs/{id}             DeleteBlog
+//	POST   /v1/blogs/{id}:restore     RestoreBlog
+//	POST   /v1/blogs/{id}:purge       PurgeBlog
+//	GET    /v1/blogs/{id}/revisions   ListBlogRevisions
+//	GET    /v1/tags                   ListTags
+//
+// and the CalculatorService routes, all POST, are /v1/calculator/sum,
+// /v1/calculator/square-root, /v1/calculator/prime-number-decomposition,
+// /v1/calculator/compute-average and /v1/calculator/find-maximum.
+func New(blog blogpb.BlogServiceClient, calculator calculatorpb.CalculatorServiceClient) *Handler {
+	h := &Handler{}
+	if blog != nil {
+		h.routes = append(h.routes, blogRoutes(blog)...)
+	}
+	if calculator != nil {
+		h.routes = append(h.routes, calculatorRoutes(calculator)...)
+	}
+	return h
+}
+
+func newRoute(method, path string, call func(ctx context.Context, r *request) (interface{}, error)) route {
+	return route{method: method, path: splitPath(path), call: call}
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// match returns the values of the {name} segments of rt if path matches it.
+func (rt *route) match(path []string) (map[string]string, bool) {
+	if len(path) != len(rt.path) {
+		return nil, false
+	}
+
+	vars := map[string]string{}
+	for i, segment := range rt.path {
+		if !strings.HasPrefix(segment, "{") {
+			if path[i] != segment {
+				return nil, false
+			}
+			continue
+		}
+
+		// a variable may be followed by a custom method, as in {id}:restore
+		end := strings.Index(segment, "}")
+		verb := segment[end+1:]
+		value := path[i]
+		if verb != "" {
+			if !strings.HasSuffix(value, verb) {
+				return nil, false
+			}
+			value = strings.TrimSuffix(value, verb)
+		} else if strings.Contains(value, ":") {
+			// a plain variable does not match the custom method of another route
+			return nil, false
+		}
+		if value == "" {
+			return nil, false
+		}
+		vars[segment[1:end]] = value
+	}
+	return vars, true
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := splitPath(r.URL.Path)
+
+	var allowed []string
+	for _, rt := range h.routes {
+		vars, ok := rt.match(path)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			allowed = append(allowed, rt.method)
+			continue
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		res, err := rt.call(outgoingContext(r), &request{Request: r, vars: vars})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeResponse(w, res)
+		return
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeStatus(w, http.StatusMethodNotAllowed, status.Newf(codes.Unimplemented, "method %s is not allowed on %s", r.Method, r.URL.Path))
+		return
+	}
+	writeError(w, status.Errorf(codes.NotFound, "no route for %s", r.URL.Path))
+}
+
+// outgoingContext returns the context of r with its Authorization header as
+// the authorization metadata of the gRPC call, so that REST callers use the
+// same bearer tokens as gRPC ones, its X-Request-Id header as the request ID
+// the call is logged with, and its traceparent and tracestate headers as the
+// trace the call joins.
+func outgoingContext(r *http.Request) context.Context {
+	ctx := tracing.Extract(r.Context(), r.Header.Get(tracing.TraceparentHeader), r.Header.Get(tracing.TracestateHeader))
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", authorization)
+	}
+	if id := r.Header.Get(logging.RequestIDHeader); id != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, logging.RequestIDHeader, id)
+	}
+	return ctx
+}
+
+// httpStatus is the HTTP status of an error with the gRPC code c, as listed
+// in google/rpc/code.proto.
+func httpStatus(c codes.Code) int {
+	switch c {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	// Unknown, Internal, DataLoss and codes this table does not know
+	return http.StatusInternalServerError
+}
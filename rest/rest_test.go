@@ -0,0 +1,266 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mirageruler/grpc-go-course/blog/auth"
+	"github.com/mirageruler/grpc-go-course/blog/blogpb"
+	"github.com/mirageruler/grpc-go-course/blog/blogservice"
+	"github.com/mirageruler/grpc-go-course/blog/store"
+	"github.com/mirageruler/grpc-go-course/blog/validate"
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorpb"
+	"github.com/mirageruler/grpc-go-course/calculator/calculatorservice"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+const testToken = "khoi-token"
+
+// newTestServer serves the Handler of a gRPC server with the blog and
+// calculator services, over an in-memory connection.
+func newTestServer(t *testing.T) *httptest.Server {
+	tokens := auth.TokenTable{testToken: {Subject: "khoi"}}
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			auth.UnaryServerInterceptor(tokens),
+			validate.UnaryServerInterceptor(blogservice.RequestRules),
+		),
+		grpc.ChainStreamInterceptor(
+			auth.StreamServerInterceptor(tokens),
+			validate.StreamServerInterceptor(blogservice.RequestRules),
+		),
+	)
+	blogservice.NewServer(store.NewMemoryBlogStore(), store.NewMemoryHistoryStore(), store.NewMemoryCommentStore()).Register(s)
+	calculatorservice.Register(s)
+
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("Dial() had unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(New(blogpb.NewBlogServiceClient(conn), calculatorpb.NewCalculatorServiceClient(conn)))
+	t.Cleanup(func() {
+		ts.Close()
+		conn.Close()
+		s.Stop()
+	})
+	return ts
+}
+
+// do sends a request with the test token and returns the status and the
+// decoded JSON body of the response.
+func do(t *testing.T, ts *httptest.Server, method, path, body string) (int, map[string]interface{}) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest(%s %s) had unexpected error: %v", method, path, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s had unexpected error: %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	var got map[string]interface{}
+	data, _ := io.ReadAll(res.Body)
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("%s %s got body %s, want a JSON object", method, path, data)
+	}
+	return res.StatusCode, got
+}
+
+func TestHandler_BlogCRUD(t *testing.T) {
+	ts := newTestServer(t)
+
+	code, got := do(t, ts, http.MethodPost, "/v1/blogs", `{"title": "Hello", "content": "# Hi", "tags": ["Go"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/blogs got status %d, want %d: %v", code, http.StatusOK, got)
+	}
+	blog := got["blog"].(map[string]interface{})
+	id := blog["id"].(string)
+	if blog["authorId"] != "khoi" {
+		t.Errorf("POST /v1/blogs got author %v, want the caller khoi", blog["authorId"])
+	}
+
+	code, got = do(t, ts, http.MethodPatch, "/v1/blogs/"+id, `{"title": "Hello again"}`)
+	if code != http.StatusOK {
+		t.Fatalf("PATCH /v1/blogs/%s got status %d, want %d: %v", id, code, http.StatusOK, got)
+	}
+
+	code, got = do(t, ts, http.MethodGet, "/v1/blogs/"+id+"?render_format=PLAIN_TEXT", "")
+	blog = got["blog"].(map[string]interface{})
+	if code != http.StatusOK || blog["title"] != "Hello again" || blog["content"] != "Hi" {
+		t.Errorf("GET /v1/blogs/%s got %d %v, want the patched title and the unchanged content as plain text", id, code, got)
+	}
+
+	code, got = do(t, ts, http.MethodGet, "/v1/blogs?tags=go&pageSize=10", "")
+	if blogs, _ := got["blogs"].([]interface{}); code != http.StatusOK || len(blogs) != 1 {
+		t.Errorf("GET /v1/blogs got %d %v, want the blog", code, got)
+	}
+
+	code, got = do(t, ts, http.MethodDelete, "/v1/blogs/"+id+"?expected_revision=1", "")
+	if code != http.StatusConflict {
+		t.Errorf("DELETE /v1/blogs/%s of a stale revision got status %d, want %d: %v", id, code, http.StatusConflict, got)
+	}
+	code, got = do(t, ts, http.MethodDelete, "/v1/blogs/"+id, "")
+	if code != http.StatusOK || got["blogId"] != id {
+		t.Errorf("DELETE /v1/blogs/%s got %d %v, want the deleted blog id", id, code, got)
+	}
+
+	code, got = do(t, ts, http.MethodGet, "/v1/blogs/"+id, "")
+	if code != http.StatusNotFound || got["code"] != float64(codes.NotFound) {
+		t.Errorf("GET /v1/blogs/%s of a deleted blog got %d %v, want %d with code NotFound", id, code, got, http.StatusNotFound)
+	}
+
+	code, got = do(t, ts, http.MethodPost, "/v1/blogs/"+id+":restore", "")
+	if code != http.StatusOK {
+		t.Errorf("POST /v1/blogs/%s:restore got status %d, want %d: %v", id, code, http.StatusOK, got)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/v1/blogs", `{"title": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/blogs", `{"content": "no title"}`, http.StatusBadRequest},
+		{http.MethodGet, "/v1/blogs?colour=red", "", http.StatusBadRequest},
+		{http.MethodGet, "/v1/blogs?page_size=many", "", http.StatusBadRequest},
+		{http.MethodGet, "/v1/blogs/not-an-id", "", http.StatusBadRequest},
+		{http.MethodPatch, "/v1/blogs/61a0f0f0f0f0f0f0f0f0f0f0", `{}`, http.StatusBadRequest},
+		{http.MethodGet, "/v1/comments", "", http.StatusNotFound},
+		{http.MethodPut, "/v1/blogs", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/v1/calculator/square-root", `{"number": -4}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		code, got := do(t, ts, tt.method, tt.path, tt.body)
+		if code != tt.want {
+			t.Errorf("%s %s got status %d, want %d: %v", tt.method, tt.path, code, tt.want, got)
+		}
+		if _, ok := got["message"]; !ok {
+			t.Errorf("%s %s got body %v, want a status with a message", tt.method, tt.path, got)
+		}
+	}
+}
+
+func TestHandler_Unauthenticated(t *testing.T) {
+	ts := newTestServer(t)
+
+	res, err := http.Post(ts.URL+"/v1/blogs", "application/json", strings.NewReader(`{"title": "Hello", "content": "Hi"}`))
+	if err != nil {
+		t.Fatalf("POST /v1/blogs had unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST /v1/blogs without a token got status %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestHandler_Calculator(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		path, body, want string
+	}{
+		{"/v1/calculator/sum", `{"firstNumber": 3, "second_number": 10}`, `{"sumResult":13}`},
+		{"/v1/calculator/square-root", `{"number": 16}`, `{"sqrtNumber":4}`},
+		{"/v1/calculator/compute-average", `[{"number": 1}, {"number": 2}, {"number": 6}]`, `{"average":3}`},
+		{"/v1/calculator/find-maximum", `[{"number": 1}, {"number": 5}, {"number": 3}, {"number": 6}]`, `[{"maxNumber":1},{"maxNumber":5},{"maxNumber":6}]`},
+	}
+
+	for _, tt := range tests {
+		res, err := http.Post(ts.URL+tt.path, "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("POST %s had unexpected error: %v", tt.path, err)
+		}
+		data, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		// protojson may vary its spacing, which the comparison ignores
+		if got := strings.ReplaceAll(string(data), " ", ""); res.StatusCode != http.StatusOK || got != tt.want {
+			t.Errorf("POST %s got %d %s, want %d %s", tt.path, res.StatusCode, got, http.StatusOK, tt.want)
+		}
+	}
+}
+
+func TestDecodeQuery(t *testing.T) {
+	query := url.Values{
+		"page_size":  {"5"},
+		"sortOrder":  {"NEWEST_FIRST"},
+		"trash":      {"2"},
+		"tags":       {"go", "grpc"},
+		"author_id":  {"khoi"},
+		"page_token": {""},
+	}
+	got := &blogpb.ListBlogRequest{}
+	if err := decodeQuery(query, got); err != nil {
+		t.Fatalf("decodeQuery(%v) had unexpected error: %v", query, err)
+	}
+	want := &blogpb.ListBlogRequest{
+		PageSize:  5,
+		SortOrder: blogpb.ListBlogRequest_NEWEST_FIRST,
+		Trash:     blogpb.ListBlogRequest_WITH_TRASHED,
+		Tags:      []string{"go", "grpc"},
+		AuthorId:  "khoi",
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("decodeQuery(%v) got %v, want %v", query, got, want)
+	}
+
+	mask := &blogpb.UpdateBlogRequest{}
+	if err := decodeQuery(url.Values{"update_mask": {"title,tags"}}, mask); err != nil {
+		t.Fatalf("decodeQuery(update_mask) had unexpected error: %v", err)
+	}
+	if want := (&fieldmaskpb.FieldMask{Paths: []string{"title", "tags"}}); !proto.Equal(mask.UpdateMask, want) {
+		t.Errorf("decodeQuery(update_mask) got %v, want %v", mask.UpdateMask, want)
+	}
+
+	if err := decodeQuery(url.Values{"blog": {"x"}}, &blogpb.UpdateBlogRequest{}); err == nil {
+		t.Errorf("decodeQuery(blog) got no error, want one for a message field")
+	}
+}
+
+func TestRoute_Match(t *testing.T) {
+	restore := newRoute(http.MethodPost, "/v1/blogs/{id}:restore", nil)
+	read := newRoute(http.MethodGet, "/v1/blogs/{id}", nil)
+
+	tests := []struct {
+		rt   route
+		path string
+		want string // the id, empty for no match
+	}{
+		{restore, "/v1/blogs/abc:restore", "abc"},
+		{restore, "/v1/blogs/abc", ""},
+		{restore, "/v1/blogs/:restore", ""},
+		{read, "/v1/blogs/abc", "abc"},
+		{read, "/v1/blogs/abc:restore", ""},
+		{read, "/v1/blogs/abc/revisions", ""},
+	}
+
+	for _, tt := range tests {
+		vars, ok := tt.rt.match(splitPath(tt.path))
+		if got := vars["id"]; ok != (tt.want != "") || got != tt.want {
+			t.Errorf("match(%s) got %q, %v, want %q", tt.path, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package rpcerr builds gRPC status errors that carry the structured details
+// of google/rpc/error_details.proto, and unpacks those details for clients.
+package rpcerr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// New returns a status error with the given code and message, carrying
+// details. Details are dropped for codes.OK, which cannot carry any.
+func New(code codes.Code, msg string, details ...protoiface.MessageV1) error {
+	st := status.New(code, msg)
+	if len(details) > 0 {
+		if withDetails, err := st.WithDetails(details...); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+// Info returns an ErrorInfo detail. reason is an UPPER_SNAKE_CASE constant
+// identifying the cause within domain, the service that produced the error.
+func Info(reason, domain string, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   domain,
+		Metadata: metadata,
+	}
+}
+
+// Resource returns a ResourceInfo detail naming the resource an error is about.
+func Resource(resourceType, name, description string) *errdetails.ResourceInfo {
+	return &errdetails.ResourceInfo{
+		ResourceType: resourceType,
+		ResourceName: name,
+		Description:  description,
+	}
+}
+
+// Violation returns a field violation for a BadRequest detail.
+func Violation(field, description string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	}
+}
+
+// BadRequest returns a BadRequest detail listing what is wrong with the
+// fields of a request.
+func BadRequest(violations ...*errdetails.BadRequest_FieldViolation) *errdetails.BadRequest {
+	return &errdetails.BadRequest{FieldViolations: violations}
+}
+
+// InfoOf returns the ErrorInfo detail of err, or nil if it has none.
+func InfoOf(err error) *errdetails.ErrorInfo {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// ReasonOf returns the reason of the ErrorInfo detail of err, or "".
+func ReasonOf(err error) string {
+	return InfoOf(err).GetReason()
+}
+
+// ResourceOf returns the ResourceInfo detail of err, or nil if it has none.
+func ResourceOf(err error) *errdetails.ResourceInfo {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ResourceInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// ViolationsOf returns the field violations of the BadRequest details of err.
+func ViolationsOf(err error) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range status.Convert(err).Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			violations = append(violations, br.GetFieldViolations()...)
+		}
+	}
+	return violations
+}
+
+// Describe formats err with its code, message and details on one line each,
+// for clients that show errors to people.
+func Describe(err error) string {
+	st := status.Convert(err)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: %s", st.Code(), st.Message())
+	if info := InfoOf(err); info != nil {
+		fmt.Fprintf(&b, "\n  reason: %s (%s)", info.GetReason(), info.GetDomain())
+		keys := make([]string, 0, len(info.GetMetadata()))
+		for k := range info.GetMetadata() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "\n  %s: %s", k, info.GetMetadata()[k])
+		}
+	}
+	if resource := ResourceOf(err); resource != nil {
+		fmt.Fprintf(&b, "\n  resource: %s %s", resource.GetResourceType(), resource.GetResourceName())
+	}
+	for _, v := range ViolationsOf(err) {
+		fmt.Fprintf(&b, "\n  field %s: %s", v.GetField(), v.GetDescription())
+	}
+
+	return b.String()
+}
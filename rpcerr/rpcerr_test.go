@@ -0,0 +1,62 @@
+package rpcerr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNew(t *testing.T) {
+	err := New(
+		codes.NotFound,
+		"Cannot find blog",
+		Info("NOT_FOUND", "blog.example", map[string]string{"b": "2", "a": "1"}),
+		Resource("blog", "42", "Cannot find blog"),
+		BadRequest(Violation("blog_id", "must exist")),
+	)
+
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("New() got code %v, want %v", code, codes.NotFound)
+	}
+	if got := ReasonOf(err); got != "NOT_FOUND" {
+		t.Errorf("ReasonOf() got %q, want %q", got, "NOT_FOUND")
+	}
+	if got := InfoOf(err).GetDomain(); got != "blog.example" {
+		t.Errorf("InfoOf() got domain %q, want %q", got, "blog.example")
+	}
+	if got := ResourceOf(err).GetResourceName(); got != "42" {
+		t.Errorf("ResourceOf() got name %q, want %q", got, "42")
+	}
+	if got := ViolationsOf(err); len(got) != 1 || got[0].GetField() != "blog_id" {
+		t.Errorf("ViolationsOf() got %v, want one violation of blog_id", got)
+	}
+
+	want := strings.Join([]string{
+		"NotFound: Cannot find blog",
+		"  reason: NOT_FOUND (blog.example)",
+		"  a: 1",
+		"  b: 2",
+		"  resource: blog 42",
+		"  field blog_id: must exist",
+	}, "\n")
+	if got := Describe(err); got != want {
+		t.Errorf("Describe() got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWithoutDetails(t *testing.T) {
+	if err := New(codes.OK, "", Info("IGNORED", "example", nil)); err != nil {
+		t.Errorf("New(OK) got %v, want nil", err)
+	}
+
+	err := errors.New("plain")
+	if InfoOf(err) != nil || ResourceOf(err) != nil || ViolationsOf(err) != nil || ReasonOf(err) != "" {
+		t.Errorf("details of a plain error got some, want none")
+	}
+	if got, want := Describe(err), "Unknown: plain"; got != want {
+		t.Errorf("Describe(plain) got %q, want %q", got, want)
+	}
+}
@@ -2,6 +2,18 @@
 
 export PATH="$PATH:$(go env GOPATH)/bin"
 
+# blog.proto imports google/rpc/status.proto, which comes with the googleapis
+# protos rather than with protoc. Point GOOGLEAPIS at a checkout of them, by
+# default third_party/googleapis:
+#
+#   git clone --depth 1 https://github.com/googleapis/googleapis third_party/googleapis
+GOOGLEAPIS="${GOOGLEAPIS:-third_party/googleapis}"
+if [ ! -f "$GOOGLEAPIS/google/rpc/status.proto" ]; then
+	echo "$GOOGLEAPIS/google/rpc/status.proto not found, see $0 to get the googleapis protos" >&2
+	exit 1
+fi
+
 protoc greet/greetpb/greet.proto --go_out=plugins=grpc:.
 protoc calculator/calculatorpb/calculator.proto --go_out=plugins=grpc:.
-protoc blog/blogpb/blog.proto --go_out=plugins=grpc:.
\ No newline at end of file
+protoc -I . -I "$GOOGLEAPIS" blog/blogpb/blog.proto --go_out=plugins=grpc:.
+protoc blog/blogpb/comment.proto --go_out=plugins=grpc:.
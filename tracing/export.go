@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Exporters are the names NewExporter knows.
+var Exporters = []string{"none", "stdout", "file"}
+
+// NewExporter returns the exporter with the name: none exports nothing, and
+// is nil, stdout writes the spans to the standard output as JSON lines, and
+// file appends them to the file at path.
+func NewExporter(name, path string) (Exporter, error) {
+	switch name {
+	case "none":
+		return nil, nil
+	case "stdout":
+		return NewJSONExporter(os.Stdout), nil
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("the file exporter needs a file")
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		e := NewJSONExporter(f)
+		e.closer = f
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q, want one of %v", name, Exporters)
+}
+
+// JSONExporter writes every span as a line of JSON, as soon as it ends.
+type JSONExporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer // of the file NewExporter opened, if any
+}
+
+// NewJSONExporter returns a JSONExporter writing to w, which it never closes.
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+func (e *JSONExporter) ExportSpan(s *Span) error {
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(line, '\n'))
+	return err
+}
+
+func (e *JSONExporter) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// The metadata keys of the W3C trace context.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// maxTraceStateLength bounds the tracestate passed on, as the W3C
+// recommendation allows 32 entries of up to 256 characters and a bit more.
+const maxTraceStateLength = 512
+
+// Extract returns ctx carrying the remote span of the traceparent and
+// tracestate headers, or ctx as it is when traceparent is missing or invalid.
+func Extract(ctx context.Context, traceparent, tracestate string) context.Context {
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	if len(tracestate) <= maxTraceStateLength {
+		sc.TraceState = tracestate
+	}
+	return ContextWithRemote(ctx, sc)
+}
+
+// incoming returns ctx carrying the remote span of the metadata of a call.
+func incoming(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return Extract(ctx, first(md, TraceparentHeader), strings.Join(md.Get(TracestateHeader), ","))
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// outgoing returns ctx with sc as the trace context of the calls made with
+// it, replacing any the caller set.
+func outgoing(ctx context.Context, sc SpanContext) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		md.Set(TracestateHeader, sc.TraceState)
+	} else {
+		delete(md, TracestateHeader)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// startRPC starts the span of a call of fullMethod, /package.Service/Method.
+func (t *Tracer) startRPC(ctx context.Context, fullMethod string, kind Kind) (context.Context, *Span) {
+	ctx, s := t.Start(ctx, strings.TrimPrefix(fullMethod, "/"), kind)
+	s.SetAttribute("rpc.system", "grpc")
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		s.SetAttribute("rpc.service", strings.TrimPrefix(fullMethod[:i], "/"))
+		s.SetAttribute("rpc.method", fullMethod[i+1:])
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		s.SetAttribute("net.peer", p.Addr.String())
+	}
+	return ctx, s
+}
+
+// UnaryServerInterceptor records a span for every unary call, joining the
+// trace of the client.
+func UnaryServerInterceptor(t *Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, s := t.startRPC(incoming(ctx), info.FullMethod, Server)
+		res, err := handler(ctx, req)
+		s.End(err)
+		return res, err
+	}
+}
+
+// StreamServerInterceptor records a span for every streaming call, joining
+// the trace of the client.
+func StreamServerInterceptor(t *Tracer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, s := t.startRPC(incoming(ss.Context()), info.FullMethod, Server)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		s.End(err)
+		return err
+	}
+}
+
+// serverStream gives the handler of a stream the context with its span.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// UnaryClientInterceptor records a span for every unary call, and sends its
+// trace context to the server.
+func UnaryClientInterceptor(t *Tracer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, s := t.startRPC(ctx, method, Client)
+		err := invoker(outgoing(ctx, s.Context()), method, req, reply, cc, opts...)
+		s.End(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor records a span for every streaming call, and sends
+// its trace context to the server. Like the metrics of the call, the span
+// ends when receiving from the stream reports its end.
+func StreamClientInterceptor(t *Tracer) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, s := t.startRPC(ctx, method, Client)
+		cs, err := streamer(outgoing(ctx, s.Context()), desc, cc, method, opts...)
+		if err != nil {
+			s.End(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, s: s, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	s             *Span
+	serverStreams bool
+}
+
+func (cs *clientStream) RecvMsg(m interface{}) error {
+	err := cs.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		cs.s.End(nil)
+	case err != nil:
+		cs.s.End(err)
+	case !cs.serverStreams:
+		// the single response of a client stream ends the call
+		cs.s.End(nil)
+	}
+	return err
+}
@@ -0,0 +1,286 @@
+// Package tracing follows calls across the servers of the course: it
+// propagates the W3C trace context of a call in its traceparent and
+// tracestate headers, records a span for every call and MongoDB command, and
+// hands the finished spans to an Exporter.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// TraceID identifies a trace, the spans of one request end to end.
+type TraceID [16]byte
+
+// SpanID identifies a span within its trace.
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether id is set, the zero ID being invalid.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// IsValid reports whether id is set, the zero ID being invalid.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+func (id TraceID) MarshalText() ([]byte, error) { return []byte(id.String()), nil }
+func (id SpanID) MarshalText() ([]byte, error)  { return []byte(id.String()), nil }
+
+// SpanContext is what a span passes on to its children, in the same process
+// or, through the traceparent and tracestate headers, in another one.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool   // whether the spans of the trace are exported
+	TraceState string // of other tracing systems, passed on as it is
+}
+
+// IsValid reports whether sc identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats sc as the value of a traceparent header.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses the value of a traceparent header,
+// version-traceid-parentid-flags in lowercase hex. Versions after 00 are read
+// as far as 00 goes, as the W3C recommendation asks.
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+	version, err := decodeHex(s[:2], 1)
+	if err != nil || version[0] == 0xff {
+		return sc, fmt.Errorf("invalid traceparent version %q", s[:2])
+	}
+	if len(s) > 55 && (version[0] == 0 || s[55] != '-') {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+
+	traceID, err := decodeHex(s[3:35], len(sc.TraceID))
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace ID %q", s[3:35])
+	}
+	spanID, err := decodeHex(s[36:52], len(sc.SpanID))
+	if err != nil {
+		return sc, fmt.Errorf("invalid parent ID %q", s[36:52])
+	}
+	flags, err := decodeHex(s[53:55], 1)
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace flags %q", s[53:55])
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: zero ID", s)
+	}
+	return sc, nil
+}
+
+// decodeHex decodes n bytes of lowercase hex.
+func decodeHex(s string, n int) ([]byte, error) {
+	if len(s) != 2*n || strings.ToLower(s) != s {
+		return nil, errors.New("not lowercase hex")
+	}
+	return hex.DecodeString(s)
+}
+
+// Kind tells what a span records, one side of a call or work within a process.
+type Kind string
+
+const (
+	Server   Kind = "server"   // a call handled
+	Client   Kind = "client"   // a call made, to a server or a database
+	Internal Kind = "internal" // work within a process, such as a command of a client
+)
+
+// Span is one operation of a trace, such as a call or a MongoDB command.
+type Span struct {
+	TraceID    TraceID           `json:"trace_id"`
+	SpanID     SpanID            `json:"span_id"`
+	ParentID   *SpanID           `json:"parent_id,omitempty"` // none for the root of a trace
+	Service    string            `json:"service,omitempty"`
+	Name       string            `json:"name"`
+	Kind       Kind              `json:"kind"`
+	Start      time.Time         `json:"start"`
+	DurationMS float64           `json:"duration_ms"`
+	Code       string            `json:"code"` // the gRPC status code it ended with
+	Error      string            `json:"error,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	tracer     *Tracer
+	sampled    bool
+	traceState string
+	once       sync.Once
+}
+
+// Context returns the SpanContext the children of s are started from.
+func (s *Span) Context() SpanContext {
+	return SpanContext{TraceID: s.TraceID, SpanID: s.SpanID, Sampled: s.sampled, TraceState: s.traceState}
+}
+
+// SetAttribute records a key and value describing s. It must not be called
+// once s has ended.
+func (s *Span) SetAttribute(key, value string) {
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]string)
+	}
+	s.Attributes[key] = value
+}
+
+// End ends s with the status of err, and exports it if its trace is sampled.
+// Only the first call has any effect.
+func (s *Span) End(err error) {
+	s.once.Do(func() {
+		s.DurationMS = float64(time.Since(s.Start).Microseconds()) / 1000
+		st := status.Convert(err)
+		s.Code = st.Code().String()
+		if err != nil {
+			s.Error = st.Message()
+		}
+		if s.sampled {
+			s.tracer.export(s)
+		}
+	})
+}
+
+// Exporter sends finished spans where they can be looked at.
+type Exporter interface {
+	ExportSpan(s *Span) error
+	// Close exports what is left to export and releases the exporter.
+	Close() error
+}
+
+// Tracer starts spans, and exports them once they end.
+type Tracer struct {
+	mu       sync.RWMutex
+	service  string
+	exporter Exporter // none when nil
+}
+
+// DefaultTracer is the tracer the servers and clients record their spans
+// with. Until it is configured it exports nothing, but still propagates the
+// trace context of calls.
+var DefaultTracer = New("", nil)
+
+// New returns a Tracer exporting the spans of service to e, or nowhere when
+// e is nil.
+func New(service string, e Exporter) *Tracer {
+	return &Tracer{service: service, exporter: e}
+}
+
+// Configure makes t export the spans of service to e from now on, or nowhere
+// when e is nil.
+func (t *Tracer) Configure(service string, e Exporter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.service, t.exporter = service, e
+}
+
+// Close closes the exporter of t.
+func (t *Tracer) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.exporter == nil {
+		return nil
+	}
+	err := t.exporter.Close()
+	t.exporter = nil
+	return err
+}
+
+func (t *Tracer) export(s *Span) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.exporter == nil {
+		return
+	}
+	if err := t.exporter.ExportSpan(s); err != nil {
+		log.Printf("failed to export span %s: %v", s.Name, err)
+	}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// Start starts a span named name, as a child of the span of ctx, or of the
+// remote span ctx carries, or as the root of a new sampled trace. The
+// returned context carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	t.mu.RLock()
+	service := t.service
+	t.mu.RUnlock()
+
+	s := &Span{SpanID: newSpanID(), Service: service, Name: name, Kind: kind, Start: time.Now(), tracer: t}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		s.TraceID, s.sampled, s.traceState = parent.TraceID, parent.Sampled, parent.TraceState
+		s.ParentID = &parent.SpanID
+	} else {
+		s.TraceID, s.sampled = newTraceID(), true
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// SpanFromContext returns the span of ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext returns the context of the span of ctx, or the
+// remote one ctx carries, or an invalid SpanContext outside of any trace.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.Context()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithRemote returns ctx carrying sc, a span of another process, so
+// that the spans started from it join its trace.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// TraceIDFromContext returns the trace ID of ctx, or "" outside of any trace.
+func TraceIDFromContext(ctx context.Context) string {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID.String()
+	}
+	return ""
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
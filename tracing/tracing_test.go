@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// recorder is an Exporter keeping the spans it exports.
+type recorder struct{ spans []*Span }
+
+func (r *recorder) ExportSpan(s *Span) error { r.spans = append(r.spans, s); return nil }
+func (r *recorder) Close() error             { return nil }
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", false, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", true, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", true, false},
+		{"", true, false},
+	}
+
+	for _, tt := range tests {
+		sc, err := ParseTraceparent(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTraceparent(%q) got error %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if sc.Sampled != tt.sampled {
+			t.Errorf("ParseTraceparent(%q) got sampled %v, want %v", tt.in, sc.Sampled, tt.sampled)
+		}
+		// the context is passed on as version 00
+		if want := "00" + tt.in[2:55]; sc.Traceparent() != want {
+			t.Errorf("Traceparent() got %s, want %s", sc.Traceparent(), want)
+		}
+	}
+}
+
+func TestTracer_Start(t *testing.T) {
+	rec := &recorder{}
+	tracer := New("blog", rec)
+
+	ctx, root := tracer.Start(context.Background(), "root", Server)
+	_, child := tracer.Start(ctx, "child", Client)
+	child.End(status.Error(codes.NotFound, "no blog"))
+	root.End(nil)
+	root.End(nil)
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("Start() exported %d spans, want 2", len(rec.spans))
+	}
+	if root.ParentID != nil || !root.TraceID.IsValid() {
+		t.Errorf("Start() of a root got parent %v and trace %v, want a new trace", root.ParentID, root.TraceID)
+	}
+	if child.TraceID != root.TraceID || child.ParentID == nil || *child.ParentID != root.SpanID {
+		t.Errorf("Start() of a child got trace %v parent %v, want trace %v parent %v", child.TraceID, child.ParentID, root.TraceID, root.SpanID)
+	}
+	if child.Code != "NotFound" || child.Error != "no blog" || child.Service != "blog" {
+		t.Errorf("End() got code %s error %q service %q, want NotFound, no blog and blog", child.Code, child.Error, child.Service)
+	}
+
+	// the spans of a trace the caller does not sample are not exported
+	rec.spans = nil
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, s := tracer.Start(ContextWithRemote(context.Background(), remote), "unsampled", Server)
+	s.End(nil)
+	if len(rec.spans) != 0 || s.TraceID != remote.TraceID {
+		t.Errorf("Start() from an unsampled parent exported %d spans in trace %v, want none in trace %v", len(rec.spans), s.TraceID, remote.TraceID)
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := New("greet", NewJSONExporter(&buf))
+	_, s := tracer.Start(context.Background(), "greet.GreetService/Greet", Server)
+	s.SetAttribute("rpc.system", "grpc")
+	s.End(nil)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("ExportSpan() wrote %s, want a JSON line", buf.String())
+	}
+	want := map[string]interface{}{
+		"trace_id": s.TraceID.String(),
+		"span_id":  s.SpanID.String(),
+		"service":  "greet",
+		"name":     "greet.GreetService/Greet",
+		"kind":     "server",
+		"code":     "OK",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("ExportSpan() wrote %s=%v, want %v", key, got[key], value)
+		}
+	}
+	if _, ok := got["parent_id"]; ok {
+		t.Errorf("ExportSpan() wrote a parent_id for a root span")
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	if e, err := NewExporter("none", ""); e != nil || err != nil {
+		t.Errorf("NewExporter(none) got %v, %v, want nil, nil", e, err)
+	}
+	if _, err := NewExporter("file", ""); err == nil {
+		t.Errorf("NewExporter(file) without a file got no error")
+	}
+	if _, err := NewExporter("zipkin", ""); err == nil || !strings.Contains(err.Error(), "unknown trace exporter") {
+		t.Errorf("NewExporter(zipkin) got error %v, want an unknown exporter", err)
+	}
+}
+
+// TestInterceptors sends a call through the client interceptor to the server
+// one, passing the metadata along as the network would.
+func TestInterceptors(t *testing.T) {
+	rec := &recorder{}
+	tracer := New("", rec)
+	method := "/blog.BlogService/ReadBlog"
+
+	var handlerSpan *Span
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerSpan = SpanFromContext(ctx)
+		return nil, status.Error(codes.NotFound, "no blog")
+	}
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		_, err := UnaryServerInterceptor(tracer)(metadata.NewIncomingContext(context.Background(), md), req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx = Extract(ctx, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "vendor=x")
+	err := UnaryClientInterceptor(tracer)(ctx, method, nil, nil, nil, invoker)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("UnaryClientInterceptor() got error %v, want the NotFound of the handler", err)
+	}
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("interceptors exported %d spans, want a server and a client one", len(rec.spans))
+	}
+	server, client := rec.spans[0], rec.spans[1]
+	if server != handlerSpan {
+		t.Errorf("SpanFromContext() in the handler got %v, want the server span", handlerSpan)
+	}
+	if client.TraceID.String() != "0af7651916cd43dd8448eb211c80319c" || client.ParentID.String() != "b7ad6b7169203331" {
+		t.Errorf("client span got trace %v parent %v, want the ones of the caller's context", client.TraceID, client.ParentID)
+	}
+	if server.TraceID != client.TraceID || *server.ParentID != client.SpanID || server.Kind != Server {
+		t.Errorf("server span got trace %v parent %v, want a child of the client span %v", server.TraceID, server.ParentID, client.SpanID)
+	}
+	if server.traceState != "vendor=x" {
+		t.Errorf("server span got tracestate %q, want the vendor=x of the caller", server.traceState)
+	}
+	if server.Name != "blog.BlogService/ReadBlog" || server.Attributes["rpc.method"] != "ReadBlog" || server.Code != "NotFound" {
+		t.Errorf("server span got name %s attributes %v code %s, want ReadBlog ending NotFound", server.Name, server.Attributes, server.Code)
+	}
+}